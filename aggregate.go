@@ -0,0 +1,106 @@
+package btree
+
+import (
+	"cmp"
+)
+
+// Monoid defines an aggregate of the entries of a tree, such as a sum, count, minimum or maximum.
+type Monoid[K cmp.Ordered, V any, A any] struct {
+	// Identity is the aggregate of no entries.
+	Identity A
+	// Of returns the aggregate of a single entry.
+	Of func(key K, value *V) A
+	// Combine combines two aggregates, the first of keys before those of the second.
+	// It must be associative, and combining with Identity must make no change.
+	Combine func(a, b A) A
+}
+
+// AggregateTree is a BTree which keeps the aggregate of each node, and its children, for a Monoid.
+// The aggregates are cleared along the path of each write, including the nodes split or merged by it,
+// and recomputed when next asked for, so only changed nodes are aggregated again.
+// As the computed aggregates are stored in the nodes, neither method may be called concurrently with any other use of the tree.
+// Values must not be modified in place once added, only replaced with Add.
+type AggregateTree[K cmp.Ordered, V any, A any] interface {
+	BTree[K, V]
+	// Total returns the aggregate of the whole tree.
+	Total() A
+	// Aggregate returns the aggregate of the keys from lo to hi, inclusive.
+	// The stored aggregates of nodes lying entirely within the range are used, so only the nodes along the edges of the range are read.
+	Aggregate(lo, hi K) A
+}
+
+type aggregateTree[K cmp.Ordered, V any, A any] struct {
+	*bTree[K, V]
+	monoid Monoid[K, V, A]
+}
+
+func (t *aggregateTree[K, V, A]) Total() A {
+	return aggregateNode(t.rootnode, t.monoid)
+}
+
+func (t *aggregateTree[K, V, A]) Aggregate(lo, hi K) A {
+	if cmp.Less(hi, lo) {
+		return t.monoid.Identity
+	}
+	return aggregateRange(t.rootnode, lo, hi, keyBounds[K]{}, t.monoid)
+}
+
+// aggregateNode returns the aggregate of the given node, computing it, and those of its children, if not yet known.
+func aggregateNode[K cmp.Ordered, V any, A any](nd *node[K, *V], m Monoid[K, V, A]) A {
+	if nd.agg != nil {
+		return nd.agg.(A)
+	}
+	a := m.Identity
+	for i := 0; i <= len(nd.Entries); i++ {
+		if !nd.IsLeaf() {
+			a = m.Combine(a, aggregateNode(&nd.Children[i], m))
+		}
+		if i < len(nd.Entries) {
+			a = m.Combine(a, m.Of(nd.Entries[i].Key, nd.Entries[i].Value))
+		}
+	}
+	nd.agg = a
+	return a
+}
+
+// aggregateRange returns the aggregate of the keys from lo to hi, in the given node and its children.
+// The bounds are the keys of the parent entries either side of the node.
+func aggregateRange[K cmp.Ordered, V any, A any](nd *node[K, *V], lo, hi K, bounds keyBounds[K], m Monoid[K, V, A]) A {
+	if bounds.hasLo && bounds.hasHi && !cmp.Less(bounds.lo, lo) && !cmp.Less(hi, bounds.hi) {
+		// every key in the node lies within the range
+		return aggregateNode(nd, m)
+	}
+	a := m.Identity
+	for i := 0; i <= len(nd.Entries); i++ {
+		cb := bounds
+		if i > 0 {
+			cb.lo, cb.hasLo = nd.Entries[i-1].Key, true
+		}
+		if i < len(nd.Entries) {
+			cb.hi, cb.hasHi = nd.Entries[i].Key, true
+		}
+		if !nd.IsLeaf() && (!cb.hasHi || cmp.Less(lo, cb.hi)) && (!cb.hasLo || cmp.Less(cb.lo, hi)) {
+			// child may hold keys within the range
+			a = m.Combine(a, aggregateRange(&nd.Children[i], lo, hi, cb, m))
+		}
+		if i < len(nd.Entries) {
+			e := nd.Entries[i]
+			if !cmp.Less(e.Key, lo) && !cmp.Less(hi, e.Key) {
+				a = m.Combine(a, m.Of(e.Key, e.Value))
+			}
+		}
+	}
+	return a
+}
+
+// NewAggregateTree creates a new, empty, AggregateTree of the given degree, keeping the aggregates of the given Monoid.
+func NewAggregateTree[K cmp.Ordered, V any, A any](degree int, m Monoid[K, V, A]) AggregateTree[K, V, A] {
+	return newAggregateTree(degree, m)
+}
+
+func newAggregateTree[K cmp.Ordered, V any, A any](degree int, m Monoid[K, V, A]) *aggregateTree[K, V, A] {
+	return &aggregateTree[K, V, A]{
+		bTree:  newBTree[K, V](degree),
+		monoid: m,
+	}
+}
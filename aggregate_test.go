@@ -0,0 +1,115 @@
+package btree
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// minMax is the smallest and largest value of a range, when ok.
+type minMax struct {
+	min, max int
+	ok       bool
+}
+
+var minMaxMonoid = Monoid[int, int, minMax]{
+	Of: func(key int, value *int) minMax {
+		return minMax{min: *value, max: *value, ok: true}
+	},
+	Combine: func(a, b minMax) minMax {
+		if !a.ok {
+			return b
+		}
+		if !b.ok {
+			return a
+		}
+		return minMax{min: min(a.min, b.min), max: max(a.max, b.max), ok: true}
+	},
+}
+
+var sumMonoid = Monoid[int, int, int]{
+	Of: func(key int, value *int) int {
+		return *value
+	},
+	Combine: func(a, b int) int {
+		return a + b
+	},
+}
+
+// keysMonoid lists the keys in the order they are combined.
+var keysMonoid = Monoid[int, int, []int]{
+	Of: func(key int, value *int) []int {
+		return []int{key}
+	},
+	Combine: func(a, b []int) []int {
+		return append(append([]int{}, a...), b...)
+	},
+}
+
+func TestAggregateTree_Sum(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, degree := range []int{3, 4, 10} {
+		at := NewAggregateTree[int, int, int](degree, sumMonoid)
+		model := map[int]int{}
+		for i := 0; i < 3000; i++ {
+			k := rnd.Intn(400)
+			if rnd.Intn(3) > 0 {
+				v := rnd.Intn(100)
+				_ = at.Add(k, &v)
+				model[k] = v
+			} else {
+				_ = at.Remove(k)
+				delete(model, k)
+			}
+			if i%20 != 0 {
+				continue
+			}
+			lo := rnd.Intn(450) - 25
+			hi := lo + rnd.Intn(200)
+			expect, total := 0, 0
+			for k, v := range model {
+				total += v
+				if k >= lo && k <= hi {
+					expect += v
+				}
+			}
+			if sum := at.Aggregate(lo, hi); sum != expect {
+				t.Fatalf("degree %d: expected sum %d from %d to %d, found %d", degree, expect, lo, hi, sum)
+			}
+			if sum := at.Total(); sum != total {
+				t.Fatalf("degree %d: expected total %d, found %d", degree, total, sum)
+			}
+		}
+	}
+}
+
+func TestAggregateTree_MinMax(t *testing.T) {
+	at := NewAggregateTree[int, int, minMax](3, minMaxMonoid)
+	for i := 0; i < 100; i++ {
+		v := (i * 37) % 101
+		_ = at.Add(i, &v)
+	}
+	if mm := at.Aggregate(10, 20); mm.min != 3 || mm.max != 97 {
+		t.Errorf("expected min %d and max %d, found %v", 3, 97, mm)
+	}
+	if mm := at.Aggregate(200, 300); mm.ok {
+		t.Errorf("expected no aggregate of an empty range, found %v", mm)
+	}
+}
+
+func TestAggregateTree_Order(t *testing.T) {
+	at := NewAggregateTree[int, int, []int](3, keysMonoid)
+	for _, k := range rand.New(rand.NewSource(1)).Perm(200) {
+		_ = at.Add(k, &k)
+	}
+	at.DeleteRange(50, 59)
+	var expect []int
+	for k := 30; k <= 120; k++ {
+		if k < 50 || k > 59 {
+			expect = append(expect, k)
+		}
+	}
+	if keys := at.Aggregate(30, 120); !slices.Equal(keys, expect) {
+		t.Errorf("expected keys combined in order %v, found %v", expect, keys)
+	}
+}
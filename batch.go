@@ -0,0 +1,156 @@
+package btree
+
+import (
+	"cmp"
+	"sort"
+)
+
+// Batch collects a group of writes to be applied to a tree in a single pass.
+// The zero value is an empty batch ready to use.
+type Batch[K cmp.Ordered, V any] struct {
+	ops []batchOp[K, V]
+}
+
+type batchOp[K cmp.Ordered, V any] struct {
+	key    K
+	value  *V
+	remove bool
+	// index is the position of the op in the batch, before sorting.
+	index int
+}
+
+// Add adds the given key and value to the batch.
+func (bt *Batch[K, V]) Add(key K, value *V) {
+	bt.ops = append(bt.ops, batchOp[K, V]{key: key, value: value, index: len(bt.ops)})
+}
+
+// Remove adds the removal of the given key to the batch.
+func (bt *Batch[K, V]) Remove(key K) {
+	bt.ops = append(bt.ops, batchOp[K, V]{key: key, remove: true, index: len(bt.ops)})
+}
+
+// Len returns the number of writes in the batch.
+func (bt *Batch[K, V]) Len() int {
+	return len(bt.ops)
+}
+
+// Reset empties the batch.
+func (bt *Batch[K, V]) Reset() {
+	bt.ops = nil
+}
+
+// sortBatchOps returns a copy of the given writes in key order. Writes to the same key remain in the order they were added.
+func sortBatchOps[K cmp.Ordered, V any](ops []batchOp[K, V]) []batchOp[K, V] {
+	ops = append([]batchOp[K, V]{}, ops...)
+	sort.SliceStable(ops, func(i, j int) bool {
+		return cmp.Less(ops[i].key, ops[j].key)
+	})
+	return ops
+}
+
+// keyBounds are the keys of the parent entries either side of a node.
+// Every key in the node lies between them.
+type keyBounds[K cmp.Ordered] struct {
+	lo, hi       K
+	hasLo, hasHi bool
+}
+
+func (kb keyBounds[K]) contains(key K) bool {
+	return (!kb.hasLo || cmp.Less(kb.lo, key)) && (!kb.hasHi || cmp.Less(key, kb.hi))
+}
+
+// applyBatch applies the sorted writes to the tree, setting the result of each in results, at the index of the write.
+// Each descent finds the leaf for the next write, then applies all the following writes which belong in
+// the same leaf without rebalancing it. Writes which need the tree rebalancing are applied with Add or Remove.
+func (b *bTree[K, V]) applyBatch(ops []batchOp[K, V], results []error) {
+	for len(ops) > 0 {
+		leaf, bounds, e := b.leafFor(ops[0].key)
+		if e != nil {
+			// key is in a parent node
+			if ops[0].remove {
+				results[ops[0].index] = b.Remove(ops[0].key)
+			} else {
+				old := e.Value
+				e.Value = ops[0].value
+				b.usage.written(old, e.Value, true)
+				b.notifyWrite(e.Key, e.Value, old, true)
+			}
+			ops = ops[1:]
+			continue
+		}
+		applied := 0
+		for _, op := range ops {
+			if !bounds.contains(op.key) {
+				break
+			}
+			if op.remove {
+				if len(leaf.Entries) < 2 {
+					// leaf would be empty and must be merged
+					break
+				}
+				if old, err := leaf.Delete(op.key); err != nil {
+					results[op.index] = err
+				} else {
+					b.usage.removed(old)
+					b.notifyRemove(op.key, old)
+				}
+			} else {
+				_, e := leaf.keyIndex(op.key)
+				if e == nil && len(leaf.Entries) >= b.degree-1 {
+					// leaf is full and must be split
+					break
+				}
+				old, existed := leaf.Insert(op.key, op.value)
+				b.usage.written(old, op.value, existed)
+				b.notifyWrite(op.key, op.value, old, existed)
+			}
+			applied++
+		}
+		if applied == 0 {
+			op := ops[0]
+			if op.remove {
+				results[op.index] = b.Remove(op.key)
+			} else {
+				results[op.index] = b.Add(op.key, op.value)
+			}
+			applied = 1
+		}
+		ops = ops[applied:]
+	}
+}
+
+// notifyWrite sends an insert, or an update when existed, to any watchers of the given key.
+func (b *bTree[K, V]) notifyWrite(key K, value, old *V, existed bool) {
+	b.watches.notifyWrite(key, value, old, existed)
+}
+
+// notifyRemove sends a remove to any watchers of the given key.
+func (b *bTree[K, V]) notifyRemove(key K, old *V) {
+	b.watches.notifyRemove(key, old)
+}
+
+// leafFor returns the leaf node the given key belongs in, with the bounds of that leafs keys.
+// If the key is found in a parent node, its entry is returned instead.
+// The nodes are expected to be written to, so their aggregates are cleared on the way.
+func (b *bTree[K, V]) leafFor(key K) (*node[K, *V], keyBounds[K], *nodeEntry[K, *V]) {
+	var bounds keyBounds[K]
+	nd := b.rootnode
+	nd.agg = nil
+	for !nd.IsLeaf() {
+		i, e := nd.keyIndex(key)
+		if e != nil {
+			return nil, bounds, e
+		}
+		if i < 0 {
+			i = len(nd.Entries)
+		} else {
+			bounds.hi, bounds.hasHi = nd.Entries[i].Key, true
+		}
+		if i > 0 {
+			bounds.lo, bounds.hasLo = nd.Entries[i-1].Key, true
+		}
+		nd = &nd.Children[i]
+		nd.agg = nil
+	}
+	return nd, bounds, nil
+}
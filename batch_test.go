@@ -0,0 +1,86 @@
+package btree
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func TestBTree_Apply(t *testing.T) {
+	bt := createTestTree(3, 10)
+	var batch Batch[int, string]
+	a, b := "a", "b"
+	batch.Add(20, &a)
+	batch.Remove(4)
+	batch.Remove(99)
+	batch.Add(20, &b)
+	batch.Add(-1, &a)
+	batch.Remove(-1)
+	results := bt.Apply(&batch)
+	if len(results) != batch.Len() {
+		t.Fatalf("expected %d results, found %d", batch.Len(), len(results))
+	}
+	for i, err := range results {
+		if i == 2 {
+			if err == nil {
+				t.Error("Expected error removing unknown key, got nil")
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error from batch write %d.  %v", i, err)
+		}
+	}
+	if v := bt.Get(20); v == nil || *v != b {
+		t.Errorf("expected last value %s added in batch for key %d, got %v", b, 20, v)
+	}
+	if bt.Get(4) != nil || bt.Get(-1) != nil {
+		t.Error("expected keys removed by batch")
+	}
+	if err := validateTree(bt); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBTree_Apply_Random(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, degree := range []int{3, 4, 7, 32} {
+		bt := NewBTree[int, string](degree)
+		model := map[int]bool{}
+		for round := 0; round < 50; round++ {
+			var batch Batch[int, string]
+			expectErr := map[int]bool{}
+			inBatch := map[int]bool{}
+			for i := 0; i < 100; i++ {
+				key := rnd.Intn(500)
+				if rnd.Intn(3) == 0 {
+					present, seen := inBatch[key]
+					if !seen {
+						present = model[key]
+					}
+					expectErr[batch.Len()] = !present
+					batch.Remove(key)
+					inBatch[key] = false
+					continue
+				}
+				v := "-" + strconv.Itoa(key) + "-"
+				batch.Add(key, &v)
+				inBatch[key] = true
+			}
+			for k, present := range inBatch {
+				model[k] = present
+			}
+			for i, err := range bt.Apply(&batch) {
+				if (err != nil) != expectErr[i] {
+					t.Fatalf("degree %d round %d: unexpected result of write %d, %v", degree, round, i, err)
+				}
+			}
+			if err := compareToModel(bt, model); err != nil {
+				t.Fatalf("degree %d round %d: %v", degree, round, err)
+			}
+			if err := bt.Validate(); err != nil {
+				t.Fatalf("degree %d round %d: %v", degree, round, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,345 @@
+package btree
+
+import (
+	"cmp"
+	"container/list"
+	"context"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// EvictionPolicy chooses which key a BoundedTree evicts when it is over its limits.
+// The tree tells the policy of every key added, read or removed, while holding its lock.
+type EvictionPolicy[K cmp.Ordered] interface {
+	// Added records a key added to the tree, or given a new value.
+	Added(key K)
+	// Accessed records a key read from the tree.
+	Accessed(key K)
+	// Removed records a key removed from the tree, by eviction or otherwise.
+	Removed(key K)
+	// Victim returns the key to evict, given the smallest and largest keys of the tree.
+	Victim(first, last K) K
+}
+
+type smallestPolicy[K cmp.Ordered] struct{}
+
+func (smallestPolicy[K]) Added(key K)    {}
+func (smallestPolicy[K]) Accessed(key K) {}
+func (smallestPolicy[K]) Removed(key K)  {}
+func (smallestPolicy[K]) Victim(first, last K) K {
+	return first
+}
+
+type largestPolicy[K cmp.Ordered] struct{}
+
+func (largestPolicy[K]) Added(key K)    {}
+func (largestPolicy[K]) Accessed(key K) {}
+func (largestPolicy[K]) Removed(key K)  {}
+func (largestPolicy[K]) Victim(first, last K) K {
+	return last
+}
+
+// EvictSmallest returns a policy evicting the smallest key.
+func EvictSmallest[K cmp.Ordered]() EvictionPolicy[K] {
+	return smallestPolicy[K]{}
+}
+
+// EvictLargest returns a policy evicting the largest key.
+func EvictLargest[K cmp.Ordered]() EvictionPolicy[K] {
+	return largestPolicy[K]{}
+}
+
+// lruPolicy keeps its keys in a list, the most recently used at the front.
+type lruPolicy[K cmp.Ordered] struct {
+	order *list.List
+	keys  map[K]*list.Element
+}
+
+func (p *lruPolicy[K]) Added(key K) {
+	p.Accessed(key)
+}
+
+func (p *lruPolicy[K]) Accessed(key K) {
+	if el, ok := p.keys[key]; ok {
+		p.order.MoveToFront(el)
+		return
+	}
+	p.keys[key] = p.order.PushFront(key)
+}
+
+func (p *lruPolicy[K]) Removed(key K) {
+	if el, ok := p.keys[key]; ok {
+		p.order.Remove(el)
+		delete(p.keys, key)
+	}
+}
+
+func (p *lruPolicy[K]) Victim(first, last K) K {
+	return p.order.Back().Value.(K)
+}
+
+// EvictLRU returns a policy evicting the least recently added or read key.
+func EvictLRU[K cmp.Ordered]() EvictionPolicy[K] {
+	return &lruPolicy[K]{order: list.New(), keys: map[K]*list.Element{}}
+}
+
+// lfuPolicy keeps a list of keys for each use count, the most recently used at the front of each list.
+type lfuPolicy[K cmp.Ordered] struct {
+	counts map[int]*list.List
+	keys   map[K]*list.Element
+	// min is the smallest use count of any key.
+	min int
+}
+
+type lfuKey[K cmp.Ordered] struct {
+	key   K
+	count int
+}
+
+func (p *lfuPolicy[K]) Added(key K) {
+	p.Accessed(key)
+}
+
+func (p *lfuPolicy[K]) Accessed(key K) {
+	count := 1
+	if el, ok := p.keys[key]; ok {
+		count = el.Value.(lfuKey[K]).count + 1
+		p.unlink(el)
+	}
+	if count == 1 || count-1 == p.min && p.counts[p.min] == nil {
+		p.min = count
+	}
+	l := p.counts[count]
+	if l == nil {
+		l = list.New()
+		p.counts[count] = l
+	}
+	p.keys[key] = l.PushFront(lfuKey[K]{key: key, count: count})
+}
+
+func (p *lfuPolicy[K]) Removed(key K) {
+	if el, ok := p.keys[key]; ok {
+		p.unlink(el)
+		delete(p.keys, key)
+	}
+	// find the new smallest count, when its list was emptied
+	if p.counts[p.min] == nil && len(p.keys) > 0 {
+		p.min = 0
+		for c := range p.counts {
+			if p.min == 0 || c < p.min {
+				p.min = c
+			}
+		}
+	}
+}
+
+// unlink removes the given element from the list of its count, dropping the list once empty.
+func (p *lfuPolicy[K]) unlink(el *list.Element) {
+	count := el.Value.(lfuKey[K]).count
+	l := p.counts[count]
+	l.Remove(el)
+	if l.Len() == 0 {
+		delete(p.counts, count)
+	}
+}
+
+func (p *lfuPolicy[K]) Victim(first, last K) K {
+	return p.counts[p.min].Back().Value.(lfuKey[K]).key
+}
+
+// EvictLFU returns a policy evicting the least frequently added or read key.
+// Of the keys used equally often, the least recently used is evicted.
+func EvictLFU[K cmp.Ordered]() EvictionPolicy[K] {
+	return &lfuPolicy[K]{counts: map[int]*list.List{}, keys: map[K]*list.Element{}}
+}
+
+// BoundedOptions configures a BoundedTree. At least one of MaxEntries and MaxBytes should be set.
+type BoundedOptions[K cmp.Ordered, V any] struct {
+	// MaxEntries is the greatest number of keys the tree holds. Zero is no limit.
+	MaxEntries int
+	// MaxBytes is the greatest total of the sizes of the entries in the tree. Zero is no limit.
+	MaxBytes int64
+	// Size estimates the size of an entry, in bytes. It is required when MaxBytes is set.
+	Size func(key K, value *V) int64
+	// Policy chooses the keys to evict. When nil, EvictLRU is used.
+	Policy EvictionPolicy[K]
+	// OnEvict, when set, is called with each key, and its value, evicted from the tree.
+	// It is called after the tree is unlocked, so it may use the tree.
+	OnEvict func(key K, value *V)
+}
+
+// BoundedTree is a tree which holds no more than a given number of keys, or bytes,
+// evicting keys chosen by its EvictionPolicy when a write takes it over its limits.
+// The keys remaining are held in order, as in any other tree.
+// Values must not be modified in place once added, only replaced with Add, so their sizes are kept up to date.
+// A BoundedTree is safe for concurrent use.
+type BoundedTree[K cmp.Ordered, V any] struct {
+	tree *bTree[K, V]
+	opts BoundedOptions[K, V]
+	// count and bytes are the number of keys, and the total of their sizes, kept so the limits are checked without walking the tree.
+	count int
+	bytes int64
+	mu    sync.Mutex
+}
+
+func (bt *BoundedTree[K, V]) Degree() int {
+	return bt.tree.Degree()
+}
+
+func (bt *BoundedTree[K, V]) Count() int {
+	bt.mu.Lock()
+	defer bt.mu.Unlock()
+	return bt.count
+}
+
+// Bytes returns the total of the sizes of the entries in the tree, or zero when no Size is set.
+func (bt *BoundedTree[K, V]) Bytes() int64 {
+	bt.mu.Lock()
+	defer bt.mu.Unlock()
+	return bt.bytes
+}
+
+// Get returns the value of the given key, or nil if it is not in the tree, recording the read with the policy.
+func (bt *BoundedTree[K, V]) Get(key K) *V {
+	bt.mu.Lock()
+	defer bt.mu.Unlock()
+	v := bt.tree.Get(key)
+	if v != nil || bt.tree.contains(key) {
+		bt.opts.Policy.Accessed(key)
+	}
+	return v
+}
+
+// Keys returns the keys present when it is called, in order. Reading keys is not recorded with the policy.
+func (bt *BoundedTree[K, V]) Keys(ctx context.Context) <-chan K {
+	var keys []K
+	bt.mu.Lock()
+	bt.tree.rootnode.ascend(nil, false, func(e *nodeEntry[K, *V]) bool {
+		keys = append(keys, e.Key)
+		return true
+	})
+	bt.mu.Unlock()
+
+	ch := make(chan K)
+	go func(ch chan<- K) {
+		defer close(ch)
+		for _, k := range keys {
+			select {
+			case <-ctx.Done():
+				return
+			case ch <- k:
+			}
+		}
+	}(ch)
+	return ch
+}
+
+// Range calls fn with each key, and its value, from lo to hi, inclusive, in order, until fn returns false.
+// The tree is locked while fn is called, so fn must not use the tree. Reading keys is not recorded with the policy.
+func (bt *BoundedTree[K, V]) Range(lo, hi K, fn func(key K, value *V) bool) {
+	bt.mu.Lock()
+	defer bt.mu.Unlock()
+	bt.tree.rootnode.ascend(&lo, true, func(e *nodeEntry[K, *V]) bool {
+		return !cmp.Less(hi, e.Key) && fn(e.Key, e.Value)
+	})
+}
+
+// Add sets the given key to the given value, then evicts keys until the tree is within its limits.
+// The key just added may itself be evicted, if the policy chooses it.
+// Returns an error, without adding the key, if the entry alone is larger than MaxBytes,
+// or, with the key added, if the policy chooses a key which is not in the tree, which stops the eviction.
+func (bt *BoundedTree[K, V]) Add(key K, value *V) error {
+	var size int64
+	if bt.opts.Size != nil {
+		size = bt.opts.Size(key, value)
+	}
+	if bt.opts.MaxBytes > 0 && size > bt.opts.MaxBytes {
+		return fmt.Errorf("entry of %d bytes is larger than the limit of %d bytes", size, bt.opts.MaxBytes)
+	}
+	bt.mu.Lock()
+	existed := bt.tree.contains(key)
+	if existed {
+		bt.bytes -= bt.sizeOf(key, bt.tree.Get(key))
+	}
+	if err := bt.tree.Add(key, value); err != nil {
+		bt.mu.Unlock()
+		return err
+	}
+	if !existed {
+		bt.count++
+	}
+	bt.bytes += size
+	bt.opts.Policy.Added(key)
+	evicted, err := bt.evict()
+	bt.mu.Unlock()
+
+	if bt.opts.OnEvict != nil {
+		for _, e := range evicted {
+			bt.opts.OnEvict(e.Key, e.Value)
+		}
+	}
+	return err
+}
+
+// Remove removes the given key, returning an error if it is not in the tree.
+func (bt *BoundedTree[K, V]) Remove(key K) error {
+	bt.mu.Lock()
+	defer bt.mu.Unlock()
+	value := bt.tree.Get(key)
+	if err := bt.tree.Remove(key); err != nil {
+		return err
+	}
+	bt.removed(key, value)
+	return nil
+}
+
+// evict removes the keys chosen by the policy until the tree is within its limits, returning the keys removed.
+// Caller must hold the lock.
+func (bt *BoundedTree[K, V]) evict() ([]nodeEntry[K, *V], error) {
+	var evicted []nodeEntry[K, *V]
+	for bt.count > 0 && bt.overLimit() {
+		key := bt.opts.Policy.Victim(bt.tree.rootnode.firstEntry().Key, bt.tree.rootnode.lastEntry().Key)
+		value := bt.tree.Get(key)
+		if err := bt.tree.Remove(key); err != nil {
+			// a policy naming a key not in the tree would never bring it within its limits
+			return evicted, fmt.Errorf("eviction policy chose key %v, which is not in the tree", key)
+		}
+		bt.removed(key, value)
+		evicted = append(evicted, nodeEntry[K, *V]{Key: key, Value: value})
+	}
+	return evicted, nil
+}
+
+func (bt *BoundedTree[K, V]) overLimit() bool {
+	return bt.opts.MaxEntries > 0 && bt.count > bt.opts.MaxEntries ||
+		bt.opts.MaxBytes > 0 && bt.bytes > bt.opts.MaxBytes
+}
+
+// removed accounts for a key removed from the tree.
+func (bt *BoundedTree[K, V]) removed(key K, value *V) {
+	bt.count--
+	bt.bytes -= bt.sizeOf(key, value)
+	bt.opts.Policy.Removed(key)
+}
+
+func (bt *BoundedTree[K, V]) sizeOf(key K, value *V) int64 {
+	if bt.opts.Size == nil {
+		return 0
+	}
+	return bt.opts.Size(key, value)
+}
+
+// NewBoundedTree creates a new, empty, BoundedTree of the given degree, limited by the given options.
+func NewBoundedTree[K cmp.Ordered, V any](degree int, opts BoundedOptions[K, V]) *BoundedTree[K, V] {
+	if opts.MaxBytes > 0 && opts.Size == nil {
+		log.Fatalf("a Size function is required to limit the tree to MaxBytes")
+	}
+	if opts.Policy == nil {
+		opts.Policy = EvictLRU[K]()
+	}
+	return &BoundedTree[K, V]{
+		tree: newBTree[K, V](degree),
+		opts: opts,
+	}
+}
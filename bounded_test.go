@@ -0,0 +1,156 @@
+package btree
+
+import (
+	"context"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func boundedKeys(bt *BoundedTree[int, string]) []int {
+	var keys []int
+	for k := range bt.Keys(context.Background()) {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func TestBoundedTree_Policies(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		policy EvictionPolicy[int]
+		expect []int
+	}{
+		// keys 1 to 3 are added, 1 read three times then 2 read, then keys 4 and 5 added
+		{"smallest", EvictSmallest[int](), []int{3, 4, 5}},
+		{"largest", EvictLargest[int](), []int{1, 2, 3}},
+		{"lru", EvictLRU[int](), []int{2, 4, 5}},
+		{"lfu", EvictLFU[int](), []int{1, 2, 5}},
+	} {
+		var evicted []int
+		bt := NewBoundedTree[int, string](3, BoundedOptions[int, string]{
+			MaxEntries: 3,
+			Policy:     c.policy,
+			OnEvict: func(key int, value *string) {
+				evicted = append(evicted, key)
+			},
+		})
+		v := "v"
+		for i := 1; i <= 3; i++ {
+			_ = bt.Add(i, &v)
+		}
+		bt.Get(1)
+		bt.Get(1)
+		bt.Get(1)
+		bt.Get(2)
+		for i := 4; i <= 5; i++ {
+			_ = bt.Add(i, &v)
+		}
+		keys := boundedKeys(bt)
+		if !slices.Equal(keys, c.expect) {
+			t.Errorf("%s: expected keys %v, found %v", c.name, c.expect, keys)
+		}
+		if len(evicted) != 2 || bt.Count() != 3 {
+			t.Errorf("%s: expected 2 keys evicted, found %v, count %d", c.name, evicted, bt.Count())
+		}
+	}
+}
+
+func TestBoundedTree_MaxBytes(t *testing.T) {
+	bt := NewBoundedTree[int, string](3, BoundedOptions[int, string]{
+		MaxBytes: 10,
+		Size: func(key int, value *string) int64 {
+			return int64(len(*value))
+		},
+		Policy: EvictSmallest[int](),
+	})
+	for i, s := range []string{"abcd", "efg", "hi"} {
+		if err := bt.Add(i, &s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if bt.Bytes() != 9 || bt.Count() != 3 {
+		t.Errorf("expected 9 bytes in 3 keys, found %d in %d", bt.Bytes(), bt.Count())
+	}
+	long := "jklmn"
+	_ = bt.Add(3, &long)
+	if keys := boundedKeys(bt); !slices.Equal(keys, []int{1, 2, 3}) || bt.Bytes() != 10 {
+		t.Errorf("expected smallest key evicted, leaving 10 bytes, found %v and %d", keys, bt.Bytes())
+	}
+	short := "x"
+	_ = bt.Add(3, &short)
+	if bt.Bytes() != 6 {
+		t.Errorf("expected replaced value to change size to %d, found %d", 6, bt.Bytes())
+	}
+	tooBig := "abcdefghijk"
+	if err := bt.Add(9, &tooBig); err == nil || bt.Get(9) != nil {
+		t.Error("expected error adding entry larger than the limit")
+	}
+	_ = bt.Remove(1)
+	if bt.Bytes() != 3 {
+		t.Errorf("expected removed key to free its bytes, found %d", bt.Bytes())
+	}
+}
+
+func TestBoundedTree_Random(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, policy := range []EvictionPolicy[int]{EvictLRU[int](), EvictLFU[int](), EvictSmallest[int](), EvictLargest[int]()} {
+		model := map[int]bool{}
+		bt := NewBoundedTree[int, string](4, BoundedOptions[int, string]{
+			MaxEntries: 50,
+			Policy:     policy,
+			OnEvict: func(key int, value *string) {
+				if !model[key] {
+					t.Fatalf("unexpected key %d evicted", key)
+				}
+				delete(model, key)
+			},
+		})
+		v := "v"
+		for i := 0; i < 5000; i++ {
+			k := rnd.Intn(200)
+			switch rnd.Intn(3) {
+			case 0:
+				model[k] = true
+				_ = bt.Add(k, &v)
+			case 1:
+				bt.Get(k)
+			default:
+				if bt.Remove(k) == nil {
+					delete(model, k)
+				}
+			}
+			if bt.Count() > 50 || bt.Count() != len(model) {
+				t.Fatalf("expected %d keys, within limit, found %d", len(model), bt.Count())
+			}
+		}
+		if err := bt.tree.Validate(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// missingPolicy chooses a key which is never in the tree.
+type missingPolicy struct {
+	smallestPolicy[int]
+}
+
+func (missingPolicy) Victim(first, last int) int {
+	return first - 1
+}
+
+func TestBoundedTree_MissingVictim(t *testing.T) {
+	bt := NewBoundedTree[int, string](3, BoundedOptions[int, string]{MaxEntries: 2, Policy: missingPolicy{}})
+	v := "v"
+	for i := 0; i < 2; i++ {
+		if err := bt.Add(i, &v); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := bt.Add(2, &v); err == nil {
+		t.Error("Expected error from policy choosing a key not in the tree, got nil")
+	}
+	if bt.Count() != 3 || bt.Get(2) == nil {
+		t.Errorf("expected key %d added, before the eviction failed, found %d keys", 2, bt.Count())
+	}
+}
@@ -0,0 +1,518 @@
+package btree
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log"
+	"sort"
+)
+
+// bpNode is a node of a B+ tree.
+// Leaf nodes hold the keys with their values, and a link to the next leaf.
+// Parent nodes hold only separator keys, and one more child than keys.
+// The separator at index i is no greater than any key in the child at i+1, and greater than every key in the child at i.
+type bpNode[K cmp.Ordered, V any] struct {
+	keys     []K
+	values   []*V
+	children []*bpNode[K, V]
+	next     *bpNode[K, V]
+}
+
+func (n *bpNode[K, V]) isLeaf() bool {
+	return len(n.children) == 0
+}
+
+// search returns the index of the first key not less than the given key, and whether it is the key.
+func (n *bpNode[K, V]) search(key K) (int, bool) {
+	i := sort.Search(len(n.keys), func(i int) bool {
+		return !cmp.Less(n.keys[i], key)
+	})
+	return i, i < len(n.keys) && n.keys[i] == key
+}
+
+// childIndex returns the index of the child the given key belongs in.
+func (n *bpNode[K, V]) childIndex(key K) int {
+	return sort.Search(len(n.keys), func(i int) bool {
+		return cmp.Less(key, n.keys[i])
+	})
+}
+
+// firstKey returns the smallest key in this node and its children.
+func (n *bpNode[K, V]) firstKey() K {
+	for !n.isLeaf() {
+		n = n.children[0]
+	}
+	return n.keys[0]
+}
+
+// bPlusTree is a B+ tree. All the values are held in the leaves, which are linked in key order,
+// so scans follow the links from leaf to leaf, without returning to the parent nodes.
+type bPlusTree[K cmp.Ordered, V any] struct {
+	root    *bpNode[K, V]
+	degree  int
+	count   int
+	watches *watchList[K, V]
+	usage   *memoryUsage[*V]
+}
+
+func (b *bPlusTree[K, V]) Degree() int {
+	return b.degree
+}
+
+func (b *bPlusTree[K, V]) Depth() int {
+	d := 0
+	for n := b.root; !n.isLeaf(); n = n.children[0] {
+		d++
+	}
+	return d
+}
+
+func (b *bPlusTree[K, V]) IsEmpty() bool {
+	return b.count == 0
+}
+
+func (b *bPlusTree[K, V]) Count() int {
+	return b.count
+}
+
+// Keys follows the links between the leaves, from the first leaf to the last.
+func (b *bPlusTree[K, V]) Keys(ctx context.Context) <-chan K {
+	ch := make(chan K)
+	go func(ch chan<- K) {
+		defer close(ch)
+		for leaf := b.firstLeaf(); leaf != nil; leaf = leaf.next {
+			for _, k := range leaf.keys {
+				select {
+				case <-ctx.Done():
+					return
+				case ch <- k:
+				}
+			}
+		}
+	}(ch)
+	return ch
+}
+
+func (b *bPlusTree[K, V]) Get(key K) *V {
+	leaf := b.leafFor(key)
+	if i, found := leaf.search(key); found {
+		return leaf.values[i]
+	}
+	return nil
+}
+
+func (b *bPlusTree[K, V]) Add(key K, value *V) error {
+	sep, right, old, existed := b.insert(b.root, key, value)
+	if right != nil {
+		// a new root pushed up
+		b.root = &bpNode[K, V]{keys: []K{sep}, children: []*bpNode[K, V]{b.root, right}}
+		b.usage.nodes++
+	}
+	if !existed {
+		b.count++
+	}
+	b.usage.written(old, value, existed)
+	b.watches.notifyWrite(key, value, old, existed)
+	return nil
+}
+
+func (b *bPlusTree[K, V]) Remove(key K) error {
+	old, ok := b.remove(b.root, key)
+	if !ok {
+		return fmt.Errorf("key %v is unknown", key)
+	}
+	b.count--
+	b.usage.removed(old)
+	if len(b.root.keys) == 0 && !b.root.isLeaf() {
+		// root emptied by a merge, pass up its only child
+		b.root = b.root.children[0]
+		b.usage.nodes--
+	}
+	b.watches.notifyRemove(key, old)
+	return nil
+}
+
+func (b *bPlusTree[K, V]) Begin() Txn[K, V] {
+	return newTxn[K, V](b, func(commit func(treeWriter[K, V]) error) error {
+		return commit(b)
+	})
+}
+
+// Apply applies all the writes in the given batch, in key order, returning the result of each write
+// in the order they were added to the batch.
+func (b *bPlusTree[K, V]) Apply(batch *Batch[K, V]) []error {
+	results := make([]error, batch.Len())
+	for _, op := range sortBatchOps(batch.ops) {
+		if op.remove {
+			results[op.index] = b.Remove(op.key)
+		} else {
+			results[op.index] = b.Add(op.key, op.value)
+		}
+	}
+	return results
+}
+
+// Watch returns a Watcher of the keys from lo to hi, inclusive.
+func (b *bPlusTree[K, V]) Watch(lo, hi K, opts WatchOptions) *Watcher[K, V] {
+	return b.watches.watch(lo, hi, opts)
+}
+
+// holdEvents holds back the events of writes, until released.
+func (b *bPlusTree[K, V]) holdEvents() {
+	b.watches.hold()
+}
+
+// releaseEvents sends the events held back when send is true, or drops them.
+func (b *bPlusTree[K, V]) releaseEvents(send bool) {
+	b.watches.release(send)
+}
+
+// Update reads, and then modifies, the given key with a single descent to its leaf.
+// Only inserts which split the leaf, and removals which leave it too small, descend again to rebalance the tree.
+func (b *bPlusTree[K, V]) Update(key K, fn UpdateFunc[V]) error {
+	leaf := b.leafFor(key)
+	i, exists := leaf.search(key)
+	var old *V
+	if exists {
+		old = leaf.values[i]
+	}
+	value, action := fn(old, exists)
+	switch action {
+	case UpdateSet:
+		if exists {
+			leaf.values[i] = value
+			b.usage.written(old, value, true)
+			b.watches.notifyWrite(key, value, old, true)
+			return nil
+		}
+		if len(leaf.keys) < b.degree-1 {
+			leaf.keys = InsertAtIndex(key, leaf.keys, i)
+			leaf.values = InsertAtIndex(value, leaf.values, i)
+			b.count++
+			b.usage.written(nil, value, false)
+			b.watches.notifyWrite(key, value, nil, false)
+			return nil
+		}
+		return b.Add(key, value)
+	case UpdateRemove:
+		if !exists {
+			return nil
+		}
+		if leaf == b.root || len(leaf.keys) > b.minKeys() {
+			leaf.keys = RemoveAtIndex(leaf.keys, i)
+			leaf.values = RemoveAtIndex(leaf.values, i)
+			b.count--
+			b.usage.removed(old)
+			b.watches.notifyRemove(key, old)
+			return nil
+		}
+		return b.Remove(key)
+	}
+	return nil
+}
+
+func (b *bPlusTree[K, V]) CompareAndSwap(key K, old, new *V, eq func(a, b *V) bool) (bool, error) {
+	return compareAndSwap(b.Update, key, old, new, eq)
+}
+
+func (b *bPlusTree[K, V]) CompareAndDelete(key K, old *V, eq func(a, b *V) bool) (bool, error) {
+	return compareAndDelete(b.Update, key, old, eq)
+}
+
+func (b *bPlusTree[K, V]) GetOrCompute(key K, build func() (*V, error)) (*V, error) {
+	return getOrCompute(b.Update, key, build)
+}
+
+// DeleteRange removes every key from lo to hi, inclusive, returning the number of keys removed.
+// The keys are found by following the leaf links from lo, then removed one at a time.
+func (b *bPlusTree[K, V]) DeleteRange(lo, hi K) int {
+	var keys []K
+	b.scan(lo, func(key K, _ *V) bool {
+		if cmp.Less(hi, key) {
+			return false
+		}
+		keys = append(keys, key)
+		return true
+	})
+	for _, k := range keys {
+		_ = b.Remove(k)
+	}
+	return len(keys)
+}
+
+// SplitAt moves the keys less than the given key into the left tree, and the rest into the right tree.
+// Both trees are built from the leaves of this one, which is left empty, without sending any events to its watchers.
+func (b *bPlusTree[K, V]) SplitAt(key K) (left, right BTree[K, V], err error) {
+	var lefts, rights []nodeEntry[K, *V]
+	for leaf := b.firstLeaf(); leaf != nil; leaf = leaf.next {
+		for i, k := range leaf.keys {
+			e := nodeEntry[K, *V]{Key: k, Value: leaf.values[i]}
+			if cmp.Less(k, key) {
+				lefts = append(lefts, e)
+			} else {
+				rights = append(rights, e)
+			}
+		}
+	}
+	b.root, b.count = &bpNode[K, V]{}, 0
+	b.usage.reset()
+	return b.load(lefts), b.load(rights), nil
+}
+
+func (b *bPlusTree[K, V]) contains(key K) bool {
+	_, found := b.leafFor(key).search(key)
+	return found
+}
+
+// minKeys is the fewest keys a node, other than the root, may hold.
+func (b *bPlusTree[K, V]) minKeys() int {
+	return (b.degree - 1) / 2
+}
+
+func (b *bPlusTree[K, V]) firstLeaf() *bpNode[K, V] {
+	n := b.root
+	for !n.isLeaf() {
+		n = n.children[0]
+	}
+	return n
+}
+
+func (b *bPlusTree[K, V]) leafFor(key K) *bpNode[K, V] {
+	n := b.root
+	for !n.isLeaf() {
+		n = n.children[n.childIndex(key)]
+	}
+	return n
+}
+
+// scan calls fn with each key, from the given key inclusive, and its value, in order, until fn returns false.
+func (b *bPlusTree[K, V]) scan(from K, fn func(key K, value *V) bool) {
+	leaf := b.leafFor(from)
+	i, _ := leaf.search(from)
+	for ; leaf != nil; leaf, i = leaf.next, 0 {
+		for ; i < len(leaf.keys); i++ {
+			if !fn(leaf.keys[i], leaf.values[i]) {
+				return
+			}
+		}
+	}
+}
+
+// insert adds the key and value to the given node.
+// When the node is split, the separator key and the new right hand node are returned.
+// The previous value of an existing key is returned, with existed true.
+func (b *bPlusTree[K, V]) insert(nd *bpNode[K, V], key K, value *V) (K, *bpNode[K, V], *V, bool) {
+	var sep K
+	if nd.isLeaf() {
+		i, found := nd.search(key)
+		if found {
+			old := nd.values[i]
+			nd.values[i] = value
+			return sep, nil, old, true
+		}
+		nd.keys = InsertAtIndex(key, nd.keys, i)
+		nd.values = InsertAtIndex(value, nd.values, i)
+		if len(nd.keys) < b.degree {
+			return sep, nil, nil, false
+		}
+		// leaf too big, move the upper half to a new leaf, linked after it
+		m := len(nd.keys) / 2
+		right := &bpNode[K, V]{
+			keys:   append([]K{}, nd.keys[m:]...),
+			values: append([]*V{}, nd.values[m:]...),
+			next:   nd.next,
+		}
+		nd.keys, nd.values, nd.next = append([]K{}, nd.keys[:m]...), append([]*V{}, nd.values[:m]...), right
+		b.usage.nodes++
+		return right.keys[0], right, nil, false
+	}
+	i := nd.childIndex(key)
+	csep, cright, old, existed := b.insert(nd.children[i], key, value)
+	if cright == nil {
+		return sep, nil, old, existed
+	}
+	nd.keys = InsertAtIndex(csep, nd.keys, i)
+	nd.children = InsertAtIndex(cright, nd.children, i+1)
+	if len(nd.keys) < b.degree {
+		return sep, nil, old, existed
+	}
+	// parent too big, the median key moves up, dividing the two halves
+	m := len(nd.keys) / 2
+	sep = nd.keys[m]
+	right := &bpNode[K, V]{
+		keys:     append([]K{}, nd.keys[m+1:]...),
+		children: append([]*bpNode[K, V]{}, nd.children[m+1:]...),
+	}
+	nd.keys, nd.children = append([]K{}, nd.keys[:m]...), append([]*bpNode[K, V]{}, nd.children[:m+1]...)
+	b.usage.nodes++
+	return sep, right, old, existed
+}
+
+// remove deletes the key from the given node, returning its value, and false if the key is unknown.
+// Separator keys are left in place, as they still divide the children correctly.
+func (b *bPlusTree[K, V]) remove(nd *bpNode[K, V], key K) (*V, bool) {
+	if nd.isLeaf() {
+		i, found := nd.search(key)
+		if !found {
+			return nil, false
+		}
+		old := nd.values[i]
+		nd.keys = RemoveAtIndex(nd.keys, i)
+		nd.values = RemoveAtIndex(nd.values, i)
+		return old, true
+	}
+	i := nd.childIndex(key)
+	old, ok := b.remove(nd.children[i], key)
+	if ok && len(nd.children[i].keys) < b.minKeys() {
+		b.rebalance(nd, i)
+	}
+	return old, ok
+}
+
+// rebalance refills the child at the given index, which is too small, by borrowing from, or merging with, a sibling.
+func (b *bPlusTree[K, V]) rebalance(nd *bpNode[K, V], i int) {
+	switch {
+	case i > 0 && len(nd.children[i-1].keys) > b.minKeys():
+		b.borrowLeft(nd, i)
+	case i < len(nd.children)-1 && len(nd.children[i+1].keys) > b.minKeys():
+		b.borrowRight(nd, i)
+	case i > 0:
+		b.merge(nd, i-1)
+	default:
+		b.merge(nd, i)
+	}
+}
+
+// borrowLeft moves the last key of the child before the given child into it.
+func (b *bPlusTree[K, V]) borrowLeft(nd *bpNode[K, V], i int) {
+	child, left := nd.children[i], nd.children[i-1]
+	last := len(left.keys) - 1
+	if child.isLeaf() {
+		child.keys = InsertAtIndex(left.keys[last], child.keys, 0)
+		child.values = InsertAtIndex(left.values[last], child.values, 0)
+		left.keys, left.values = left.keys[:last], left.values[:last]
+		nd.keys[i-1] = child.keys[0]
+		return
+	}
+	child.keys = InsertAtIndex(nd.keys[i-1], child.keys, 0)
+	child.children = InsertAtIndex(left.children[last+1], child.children, 0)
+	nd.keys[i-1] = left.keys[last]
+	left.keys, left.children = left.keys[:last], left.children[:last+1]
+}
+
+// borrowRight moves the first key of the child after the given child into it.
+func (b *bPlusTree[K, V]) borrowRight(nd *bpNode[K, V], i int) {
+	child, right := nd.children[i], nd.children[i+1]
+	if child.isLeaf() {
+		child.keys = append(child.keys, right.keys[0])
+		child.values = append(child.values, right.values[0])
+		right.keys, right.values = RemoveAtIndex(right.keys, 0), RemoveAtIndex(right.values, 0)
+		nd.keys[i] = right.keys[0]
+		return
+	}
+	child.keys = append(child.keys, nd.keys[i])
+	child.children = append(child.children, right.children[0])
+	nd.keys[i] = right.keys[0]
+	right.keys, right.children = RemoveAtIndex(right.keys, 0), RemoveAtIndex(right.children, 0)
+}
+
+// merge combines the child at the given index with the child after it, removing the separator between them.
+func (b *bPlusTree[K, V]) merge(nd *bpNode[K, V], i int) {
+	left, right := nd.children[i], nd.children[i+1]
+	if left.isLeaf() {
+		left.keys = append(left.keys, right.keys...)
+		left.values = append(left.values, right.values...)
+		left.next = right.next
+	} else {
+		left.keys = append(append(left.keys, nd.keys[i]), right.keys...)
+		left.children = append(left.children, right.children...)
+	}
+	nd.keys = RemoveAtIndex(nd.keys, i)
+	nd.children = RemoveAtIndex(nd.children, i+1)
+	b.usage.nodes--
+}
+
+// load returns a new tree, of the same degree as this one, of the given entries, which must be in key order.
+// The leaves are filled evenly, then each level of parents built over them, from the bottom up.
+func (b *bPlusTree[K, V]) load(entries []nodeEntry[K, *V]) *bPlusTree[K, V] {
+	t := newBPlusTree[K, V](b.degree)
+	t.usage = newMemoryUsage(b.usage.sizer)
+	if len(entries) == 0 {
+		return t
+	}
+	t.count = len(entries)
+	t.usage.nodes = 0
+	for _, e := range entries {
+		t.usage.written(nil, e.Value, false)
+	}
+	var level []*bpNode[K, V]
+	for _, size := range evenSizes(len(entries), b.degree-1) {
+		leaf := &bpNode[K, V]{}
+		t.usage.nodes++
+		for _, e := range entries[:size] {
+			leaf.keys = append(leaf.keys, e.Key)
+			leaf.values = append(leaf.values, e.Value)
+		}
+		entries = entries[size:]
+		if len(level) > 0 {
+			level[len(level)-1].next = leaf
+		}
+		level = append(level, leaf)
+	}
+	for len(level) > 1 {
+		var parents []*bpNode[K, V]
+		for _, size := range evenSizes(len(level), b.degree) {
+			parent := &bpNode[K, V]{children: level[:size:size]}
+			t.usage.nodes++
+			for _, child := range parent.children[1:] {
+				parent.keys = append(parent.keys, child.firstKey())
+			}
+			level = level[size:]
+			parents = append(parents, parent)
+		}
+		level = parents
+	}
+	t.root = level[0]
+	return t
+}
+
+// evenSizes divides n into the fewest groups no larger than max, with sizes differing by no more than one.
+func evenSizes(n, max int) []int {
+	groups := (n + max - 1) / max
+	sizes := make([]int, groups)
+	for i := range sizes {
+		sizes[i] = n / groups
+		if i < n%groups {
+			sizes[i]++
+		}
+	}
+	return sizes
+}
+
+// NewBPlusTree creates a new, empty, B+ tree of the given degree.
+// Its parent nodes hold only separator keys, with all the values held in leaves, which are linked in key order.
+// Scans of the keys follow the links from leaf to leaf.
+func NewBPlusTree[K cmp.Ordered, V any](degree int) BTree[K, V] {
+	return newBPlusTree[K, V](degree)
+}
+
+// NewBPlusTreeWithSizer creates a new, empty, B+ tree which estimates the size of its values with the given Sizer,
+// in its MemoryUsage, rather than counting only the size of each value itself.
+func NewBPlusTreeWithSizer[K cmp.Ordered, V any](degree int, sizer Sizer[V]) BTree[K, V] {
+	b := newBPlusTree[K, V](degree)
+	b.usage = newMemoryUsage(sizer)
+	return b
+}
+
+func newBPlusTree[K cmp.Ordered, V any](degree int) *bPlusTree[K, V] {
+	if degree < 3 {
+		log.Fatalf("degree must be >= 3")
+	}
+	return &bPlusTree[K, V]{
+		root:    &bpNode[K, V]{},
+		degree:  degree,
+		watches: &watchList[K, V]{},
+		usage:   newMemoryUsage[V](nil),
+	}
+}
@@ -0,0 +1,132 @@
+package btree
+
+import (
+	"context"
+	"math/rand"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestBPlusTree_AddRemove(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, degree := range []int{3, 4, 5, 10} {
+		bt := NewBPlusTree[int, string](degree)
+		model := map[int]bool{}
+		for i := 0; i < 20000; i++ {
+			k := rnd.Intn(1000)
+			if rnd.Intn(2) == 0 {
+				v := strconv.Itoa(k)
+				_ = bt.Add(k, &v)
+				model[k] = true
+			} else {
+				if err := bt.Remove(k); (err == nil) != model[k] {
+					t.Fatalf("degree %d: unexpected remove result %v for key %d", degree, err, k)
+				}
+				delete(model, k)
+			}
+		}
+		if err := compareToModel(bt, model); err != nil {
+			t.Errorf("degree %d: %v", degree, err)
+		}
+		if bt.Count() != len(model) {
+			t.Errorf("degree %d: expected %d keys, found %d", degree, len(model), bt.Count())
+		}
+		if err := bt.Validate(); err != nil {
+			t.Errorf("degree %d: %v", degree, err)
+		}
+	}
+}
+
+func TestBPlusTree_Keys(t *testing.T) {
+	bt := NewBPlusTree[int, string](4)
+	for _, k := range rand.New(rand.NewSource(1)).Perm(500) {
+		_ = bt.Add(k, nil)
+	}
+	var keys []int
+	for k := range bt.Keys(context.Background()) {
+		keys = append(keys, k)
+	}
+	if len(keys) != 500 || !slices.IsSorted(keys) {
+		t.Errorf("expected %d keys in order, found %d", 500, len(keys))
+	}
+}
+
+func TestBPlusTree_Interface(t *testing.T) {
+	bt := NewBPlusTree[int, string](3)
+	for i := 0; i < 100; i++ {
+		v := strconv.Itoa(i)
+		_ = bt.Add(i, &v)
+	}
+	w := bt.Watch(0, 1000, WatchOptions{Buffer: 1000})
+	defer w.Close()
+
+	txn := bt.Begin()
+	_ = txn.Remove(5)
+	_ = txn.Add(200, nil)
+	if err := txn.Commit(); err != nil || bt.Get(5) != nil || !containsKey[int, string](bt, 200) {
+		t.Errorf("expected transaction committed, %v", err)
+	}
+	var batch Batch[int, string]
+	batch.Remove(6)
+	batch.Remove(1000)
+	if results := bt.Apply(&batch); results[0] != nil || results[1] == nil {
+		t.Errorf("unexpected batch results %v", results)
+	}
+	v := "computed"
+	if found, _ := bt.GetOrCompute(300, func() (*string, error) { return &v, nil }); found != &v {
+		t.Error("expected computed value added")
+	}
+	if ok, _ := bt.CompareAndDelete(300, &v, nil); !ok {
+		t.Error("expected compare and delete to remove key")
+	}
+	if removed := bt.DeleteRange(10, 19); removed != 10 || bt.Get(15) != nil {
+		t.Errorf("expected %d keys removed, removed %d", 10, removed)
+	}
+	if err := bt.Validate(); err != nil {
+		t.Error(err)
+	}
+	if len(w.Events()) != 2+1+2+10 {
+		t.Errorf("expected %d events, found %d", 15, len(w.Events()))
+	}
+
+	count := bt.Count()
+	left, right, err := bt.SplitAt(50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if left.Count()+right.Count() != count || left.Get(49) == nil || right.Get(50) == nil || !bt.IsEmpty() {
+		t.Errorf("unexpected split of %d keys into %d and %d", count, left.Count(), right.Count())
+	}
+	for _, tree := range []BTree[int, string]{left, right} {
+		if err := tree.Validate(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestBPlusTree_Update(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	bt := NewBPlusTree[int, string](4)
+	model := map[int]bool{}
+	for i := 0; i < 5000; i++ {
+		key := rnd.Intn(300)
+		action := UpdateAction(rnd.Intn(3))
+		_ = bt.Update(key, func(old *string, exists bool) (*string, UpdateAction) {
+			v := strconv.Itoa(key)
+			return &v, action
+		})
+		switch action {
+		case UpdateSet:
+			model[key] = true
+		case UpdateRemove:
+			delete(model, key)
+		}
+	}
+	if err := compareToModel(bt, model); err != nil {
+		t.Error(err)
+	}
+	if err := bt.Validate(); err != nil {
+		t.Error(err)
+	}
+}
@@ -15,11 +15,33 @@ type BTree[K cmp.Ordered, V any] interface {
 	Add(key K, value *V) error
 	Remove(key K) error
 	Count() int
+	Begin() Txn[K, V]
+	Apply(batch *Batch[K, V]) []error
+	Watch(lo, hi K, opts WatchOptions) *Watcher[K, V]
+	CompareAndSwap(key K, old, new *V, eq func(a, b *V) bool) (bool, error)
+	CompareAndDelete(key K, old *V, eq func(a, b *V) bool) (bool, error)
+	Update(key K, fn UpdateFunc[V]) error
+	GetOrCompute(key K, build func() (*V, error)) (*V, error)
+	DeleteRange(lo, hi K) int
+	// SplitAt moves the keys less than the given key into the left tree, and the rest into the right, leaving this tree empty.
+	// If the split fails, the tree is left unchanged.
+	SplitAt(key K) (left, right BTree[K, V], err error)
+	MemoryUsage() int64
+	Stats() TreeStats
+	Validate() error
 }
 
-type bTree[K cmp.Ordered, V any] struct {
-	rootnode *node[K, V]
+// nodeTree is the root node, of the given degree, of a tree storing values of type S in its entries,
+// with the algorithms which add and remove them, shared by BTree and ValueTree.
+type nodeTree[K cmp.Ordered, S any] struct {
+	rootnode *node[K, S]
 	degree   int
+	usage    *memoryUsage[S]
+}
+
+type bTree[K cmp.Ordered, V any] struct {
+	nodeTree[K, *V]
+	watches *watchList[K, V]
 }
 
 func (b bTree[K, V]) Degree() int {
@@ -77,29 +99,102 @@ func (b bTree[K, V]) Get(key K) *V {
 	return ne.Value
 }
 
+func (b *bTree[K, V]) Begin() Txn[K, V] {
+	return newTxn[K, V](b, func(commit func(treeWriter[K, V]) error) error {
+		return commit(b)
+	})
+}
+
+// Apply applies all the writes in the given batch, in key order, returning the result of each write
+// in the order they were added to the batch.
+func (b *bTree[K, V]) Apply(batch *Batch[K, V]) []error {
+	results := make([]error, batch.Len())
+	b.applyBatch(sortBatchOps(batch.ops), results)
+	return results
+}
+
+func (b bTree[K, V]) contains(key K) bool {
+	return b.rootnode.Get(key) != nil
+}
+
+// Watch returns a Watcher of the keys from lo to hi, inclusive.
+// Events are sent from Add and Remove, so a watcher with the OverflowBlock policy can hold up writes.
+func (b *bTree[K, V]) Watch(lo, hi K, opts WatchOptions) *Watcher[K, V] {
+	return b.watches.watch(lo, hi, opts)
+}
+
+// holdEvents holds back the events of writes, until released.
+func (b *bTree[K, V]) holdEvents() {
+	b.watches.hold()
+}
+
+// releaseEvents sends the events held back when send is true, or drops them.
+func (b *bTree[K, V]) releaseEvents(send bool) {
+	b.watches.release(send)
+}
+
 func (b *bTree[K, V]) Add(key K, value *V) error {
+	if b.watches.active() {
+		e := Event[K, V]{Type: EventInsert, Key: key, Value: value}
+		if ne := b.rootnode.Get(key); ne != nil {
+			e.Type, e.Old = EventUpdate, ne.Value
+		}
+		defer b.watches.notify(e)
+	}
+	b.insert(key, value)
+	return nil
+}
+
+func (b *bTree[K, V]) Remove(key K) error {
+	old, err := b.delete(key)
+	if err != nil {
+		return err
+	}
+	if b.watches.active() {
+		b.watches.notify(Event[K, V]{Type: EventRemove, Key: key, Old: old})
+	}
+	return nil
+}
+
+// clone returns a copy of this tree which shares its values, but none of its nodes.
+func (b bTree[K, V]) clone() *bTree[K, V] {
+	usage := *b.usage
+	return &bTree[K, V]{
+		nodeTree: nodeTree[K, *V]{rootnode: b.rootnode.clone(), degree: b.degree, usage: &usage},
+		watches:  &watchList[K, V]{},
+	}
+}
+
+// insert sets the given key to the given value.
+func (b *nodeTree[K, S]) insert(key K, value S) {
 	nn := b.add(key, value, b.rootnode)
 	if nn != nil {
 		// a new root pushed up
 		b.rootnode = nn
+		b.usage.nodes++
 	}
-	return nil
 }
 
-func (b *bTree[K, V]) Remove(key K) error {
-	nn, err := b.remove(key, b.rootnode)
+// delete removes the given key, returning the value it held, or an error if it is unknown.
+func (b *nodeTree[K, S]) delete(key K) (S, error) {
+	nn, old, err := b.remove(key, b.rootnode)
 	if err != nil {
-		return err
+		return old, err
 	}
+	b.usage.removed(old)
 	if nn != nil {
+		// root emptied by a merge, pass up its only child
 		b.rootnode = nn
+		b.usage.nodes--
 	}
-	return nil
+	return old, nil
 }
 
-func (b *bTree[K, V]) add(key K, value *V, nd *node[K, V]) *node[K, V] {
+func (b *nodeTree[K, S]) add(key K, value S, nd *node[K, S]) *node[K, S] {
+	nd.agg = nil
 	if nd.IsLeaf() {
-		nd.Insert(key, value)
+		old, existed := nd.Insert(key, value)
+		b.usage.written(old, value, existed)
 	} else {
 		nd = b.addToChild(key, value, nd)
 	}
@@ -107,13 +202,15 @@ func (b *bTree[K, V]) add(key K, value *V, nd *node[K, V]) *node[K, V] {
 		// node size within bounds, all done
 		return nil
 	}
+	b.usage.nodes++
 	return nd.Split()
 }
 
-func (b *bTree[K, V]) addToChild(key K, value *V, nd *node[K, V]) *node[K, V] {
+func (b *nodeTree[K, S]) addToChild(key K, value S, nd *node[K, S]) *node[K, S] {
 	i, e := nd.keyIndex(key)
 	if e != nil {
 		// already exists, update value
+		b.usage.written(e.Value, value, true)
 		e.Value = value
 		return nd
 	}
@@ -133,13 +230,14 @@ func (b *bTree[K, V]) addToChild(key K, value *V, nd *node[K, V]) *node[K, V] {
 	return nd
 }
 
-func (b *bTree[K, V]) remove(key K, nd *node[K, V]) (*node[K, V], error) {
+// remove removes the given key from the given node, or its children, returning the value removed.
+// The returned node, when not nil, is a child which replaces the given node, when it is left empty.
+func (b *nodeTree[K, S]) remove(key K, nd *node[K, S]) (*node[K, S], S, error) {
+	nd.agg = nil
 	if nd.IsLeaf() {
 		// leaf node simply deletes key and lets parent node balance entries. (Except root node, with no parent)
-		if err := nd.Delete(key); err != nil {
-			return nil, err
-		}
-		return nil, nil //TODO review if return node required
+		old, err := nd.Delete(key)
+		return nil, old, err
 	}
 	// non leaf / parent node
 	i, e := nd.keyIndex(key)
@@ -151,6 +249,7 @@ func (b *bTree[K, V]) remove(key K, nd *node[K, V]) (*node[K, V], error) {
 		return b.removeFromChild(key, i, nd)
 	}
 	// a parent node containing key to remove
+	old := e.Value
 
 	// replace entry to be removed with the preceding entry, from the right most leaf of the child
 	pn := nd.getPreceeedingNode(&nd.Children[i])
@@ -158,44 +257,60 @@ func (b *bTree[K, V]) remove(key K, nd *node[K, V]) (*node[K, V], error) {
 	nd.Entries[i] = *lastE
 
 	// perform a Remove of the copied entry, to remove from the leaf we stole it from and rebalnce tree
-	return b.removeFromChild(lastE.Key, i, nd)
+	nn, _, err := b.removeFromChild(lastE.Key, i, nd)
+	return nn, old, err
 }
 
-func (b *bTree[K, V]) removeFromChild(key K, childIndex int, nd *node[K, V]) (*node[K, V], error) {
+func (b *nodeTree[K, S]) removeFromChild(key K, childIndex int, nd *node[K, S]) (*node[K, S], S, error) {
 	child := &nd.Children[childIndex]
-	if _, err := b.remove(key, child); err != nil {
-		return nil, err
+	_, old, err := b.remove(key, child)
+	if err != nil {
+		return nil, old, err
 	}
 	if len(child.Entries) > 0 {
 		// child still has enough entries
-		return nil, nil
+		return nil, old, nil
 	}
 	// Child now empty, Merge into one of its peers and include the entry from this node which "bridges" the merge childre,
 	entryIndex := nd.mergeChild(childIndex)
+	b.usage.nodes--
 	mergedChild := nd.Children[entryIndex]
 	// Ensure merged child is not too big
 	if len(mergedChild.Entries) >= b.degree {
 		// merges node now too big, perform split
 		nn := mergedChild.Split()
+		b.usage.nodes++
 		nd.Entries = InsertAtIndex(nn.Entries[0], nd.Entries, entryIndex)
 		nd.Children[entryIndex] = nn.Children[0]
-		nd.Children = InsertAtIndex(nn.Children[1], nd.Children, childIndex)
+		nd.Children = InsertAtIndex(nn.Children[1], nd.Children, entryIndex+1)
 	}
 
 	if len(nd.Entries) == 0 {
 		// If this parent now empty, pass up it's first child
-		return &nd.Children[0], nil
+		return &nd.Children[0], old, nil
 	}
-	return nil, nil
+	return nil, old, nil
 }
 
 func NewBTree[K cmp.Ordered, V any](degree int) BTree[K, V] {
+	return newBTree[K, V](degree)
+}
+
+// NewBTreeWithSizer creates a new, empty, BTree which estimates the size of its values with the given Sizer,
+// in its MemoryUsage, rather than counting only the size of each value itself.
+func NewBTreeWithSizer[K cmp.Ordered, V any](degree int, sizer Sizer[V]) BTree[K, V] {
+	b := newBTree[K, V](degree)
+	b.usage = newMemoryUsage(sizer)
+	return b
+}
+
+func newBTree[K cmp.Ordered, V any](degree int) *bTree[K, V] {
 	if degree < 2 {
 		log.Fatalf("degree must be >= 2")
 	}
 
 	return &bTree[K, V]{
-		rootnode: &node[K, V]{},
-		degree:   degree,
+		nodeTree: nodeTree[K, *V]{rootnode: &node[K, *V]{}, degree: degree, usage: newMemoryUsage[V](nil)},
+		watches:  &watchList[K, V]{},
 	}
 }
@@ -292,7 +292,7 @@ func checkContains(bt BTree[int, string], count int) error {
 	return nil
 }
 
-func checkContainsEntries(n *node[int, string], entries ...int) error {
+func checkContainsEntries(n *node[int, *string], entries ...int) error {
 	if len(n.Entries) < len(entries) {
 		return fmt.Errorf("unexpected entry count, expected %d entries, found %d", len(entries), len(n.Entries))
 	}
@@ -319,7 +319,7 @@ func validateTree(bt BTree[int, string]) error {
 	btp := bt.(*bTree[int, string])
 	return validateNode(btp.rootnode, btp.degree, 0)
 }
-func validateNode(n *node[int, string], degree, depth int) error {
+func validateNode(n *node[int, *string], degree, depth int) error {
 	el := len(n.Entries)
 
 	if el >= degree {
@@ -365,6 +365,56 @@ func keyForInt(i int) string {
 	return fmt.Sprintf("%x", i)
 }
 
+func TestBTree_Add_UpdateParentKey(t *testing.T) {
+	bt := createTestTree(3, 10)
+	key := bt.rootnode.Entries[0].Key
+	v := "updated"
+	if err := bt.Add(key, &v); err != nil {
+		t.Fatal(err)
+	}
+	if got := bt.Get(key); got == nil || *got != v {
+		t.Errorf("expected updated value %q for key %d held in the root, got %v", v, key, got)
+	}
+}
+
+func TestBTree_Split_CopiesEntries(t *testing.T) {
+	nd := &node[int, *string]{}
+	for i := 0; i < 5; i++ {
+		nd.Insert(i, nil)
+	}
+	nn := nd.Split()
+	// merges append to the entries of a child, as here
+	left := &nn.Children[0]
+	left.Entries = append(left.Entries, nodeEntry[int, *string]{Key: 10}, nodeEntry[int, *string]{Key: 11})
+	right := nn.Children[1]
+	if len(right.Entries) != 2 || right.Entries[0].Key != 3 || right.Entries[1].Key != 4 {
+		t.Errorf("expected right child unchanged by insert into left child, found %v", right)
+	}
+}
+
+func TestBTree_Remove_MergeThenSplit(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, degree := range []int{4, 5, 6} {
+		bt := createTestTree(degree, 300)
+		model := map[int]bool{}
+		for i := 0; i < 300; i++ {
+			model[i] = true
+		}
+		for _, k := range rnd.Perm(300)[:250] {
+			if err := bt.Remove(k); err != nil {
+				t.Fatalf("degree %d: unexpected error removing key %d  %v", degree, k, err)
+			}
+			model[k] = false
+			if err := bt.Validate(); err != nil {
+				t.Fatalf("degree %d: after removing key %d  %v", degree, k, err)
+			}
+		}
+		if err := compareToModel(bt, model); err != nil {
+			t.Errorf("degree %d: %v", degree, err)
+		}
+	}
+}
+
 func TestCompareToHashmap(t *testing.T) {
 	count := 10000000
 	testChecks := 100000
@@ -390,6 +440,7 @@ func TestCompareToHashmap(t *testing.T) {
 		}
 	}
 	t.Logf("took %v to read tree %d times\n", time.Since(tm), testChecks)
+	t.Logf("estimated tree memory usage: %s\n", byteString(uint64(bt.MemoryUsage())))
 	showMemoryStats(&memRef)
 	runtime.GC()
 	runtime.ReadMemStats(&memRef)
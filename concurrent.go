@@ -0,0 +1,185 @@
+package btree
+
+import (
+	"cmp"
+	"context"
+	"sync"
+)
+
+// concurrentTree guards a tree with a read/write lock, making it safe for concurrent use.
+type concurrentTree[K cmp.Ordered, V any] struct {
+	tree BTree[K, V]
+	mu   sync.RWMutex
+}
+
+func (c *concurrentTree[K, V]) Degree() int {
+	return c.tree.Degree()
+}
+
+func (c *concurrentTree[K, V]) Depth() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.tree.Depth()
+}
+
+func (c *concurrentTree[K, V]) IsEmpty() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.tree.IsEmpty()
+}
+
+// Keys returns the keys present when it is called.
+// The keys are collected before returning, so the tree may be written to while the keys are read.
+func (c *concurrentTree[K, V]) Keys(ctx context.Context) <-chan K {
+	c.mu.RLock()
+	var keys []K
+	for k := range c.tree.Keys(ctx) {
+		keys = append(keys, k)
+	}
+	c.mu.RUnlock()
+
+	ch := make(chan K)
+	go func(ch chan<- K) {
+		defer close(ch)
+		for _, k := range keys {
+			select {
+			case <-ctx.Done():
+				return
+			case ch <- k:
+			}
+		}
+	}(ch)
+	return ch
+}
+
+func (c *concurrentTree[K, V]) Get(key K) *V {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.tree.Get(key)
+}
+
+func (c *concurrentTree[K, V]) Add(key K, value *V) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tree.Add(key, value)
+}
+
+func (c *concurrentTree[K, V]) Remove(key K) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tree.Remove(key)
+}
+
+func (c *concurrentTree[K, V]) Count() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.tree.Count()
+}
+
+// Begin returns a transaction whose commit holds the write lock, so readers never see it half applied.
+func (c *concurrentTree[K, V]) Begin() Txn[K, V] {
+	return newTxn[K, V](c, func(commit func(treeWriter[K, V]) error) error {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return commit(c.tree)
+	})
+}
+
+func (c *concurrentTree[K, V]) Apply(batch *Batch[K, V]) []error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tree.Apply(batch)
+}
+
+// Watch returns a Watcher of the wrapped tree.
+// Events are sent while the write lock is held, so the consumer of an OverflowBlock watcher must not use the tree.
+func (c *concurrentTree[K, V]) Watch(lo, hi K, opts WatchOptions) *Watcher[K, V] {
+	return c.tree.Watch(lo, hi, opts)
+}
+
+// holdEvents holds back the events of the wrapped tree, when it can.
+func (c *concurrentTree[K, V]) holdEvents() {
+	if h, ok := c.tree.(eventHolder); ok {
+		h.holdEvents()
+	}
+}
+
+func (c *concurrentTree[K, V]) releaseEvents(send bool) {
+	if h, ok := c.tree.(eventHolder); ok {
+		h.releaseEvents(send)
+	}
+}
+
+// Update holds the write lock while fn is called, so fn must not use the tree.
+func (c *concurrentTree[K, V]) Update(key K, fn UpdateFunc[V]) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tree.Update(key, fn)
+}
+
+func (c *concurrentTree[K, V]) CompareAndSwap(key K, old, new *V, eq func(a, b *V) bool) (bool, error) {
+	return compareAndSwap(c.Update, key, old, new, eq)
+}
+
+func (c *concurrentTree[K, V]) CompareAndDelete(key K, old *V, eq func(a, b *V) bool) (bool, error) {
+	return compareAndDelete(c.Update, key, old, eq)
+}
+
+// GetOrCompute first looks for the key under the read lock.
+// Only a missing key takes the write lock, which is held while build is called, so build must not use the tree.
+func (c *concurrentTree[K, V]) GetOrCompute(key K, build func() (*V, error)) (*V, error) {
+	c.mu.RLock()
+	v := c.tree.Get(key)
+	c.mu.RUnlock()
+	if v != nil {
+		return v, nil
+	}
+	return getOrCompute(c.Update, key, build)
+}
+
+func (c *concurrentTree[K, V]) DeleteRange(lo, hi K) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tree.DeleteRange(lo, hi)
+}
+
+// SplitAt returns the two halves of the wrapped tree, each wrapped again, or the error of the wrapped tree.
+func (c *concurrentTree[K, V]) SplitAt(key K) (left, right BTree[K, V], err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if left, right, err = c.tree.SplitAt(key); err != nil {
+		return nil, nil, err
+	}
+	return NewConcurrentBTree(left), NewConcurrentBTree(right), nil
+}
+
+// MemoryUsage holds the write lock, as the wrapped tree may count its nodes again.
+func (c *concurrentTree[K, V]) MemoryUsage() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tree.MemoryUsage()
+}
+
+func (c *concurrentTree[K, V]) Stats() TreeStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.tree.Stats()
+}
+
+func (c *concurrentTree[K, V]) Validate() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.tree.Validate()
+}
+
+func (c *concurrentTree[K, V]) contains(key K) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return containsKey(c.tree, key)
+}
+
+// NewConcurrentBTree wraps the given tree so it is safe for concurrent use.
+// The given tree should not be used directly once wrapped.
+func NewConcurrentBTree[K cmp.Ordered, V any](tree BTree[K, V]) BTree[K, V] {
+	return &concurrentTree[K, V]{tree: tree}
+}
@@ -0,0 +1,29 @@
+package btree
+
+import (
+	"cmp"
+)
+
+// Diff returns the changes which turn tree a into tree b, in key order.
+// Keys only in b are EventInsert, keys only in a are EventRemove, and keys in both, with values which differ, are EventUpdate.
+// Each change has the value from b, and the old value from a.
+// Values are compared with eq, or by pointer if eq is nil.
+// Every key of both trees is read, in a single pass. There is no fast path skipping subtrees the trees share,
+// as no tree shares its nodes: copies and snapshots copy every node, so there is no copy-on-write lineage to follow.
+func Diff[K cmp.Ordered, V any](a, b BTree[K, V], eq func(a, b *V) bool) []Event[K, V] {
+	var changes []Event[K, V]
+	if a == b {
+		return changes
+	}
+	walkTrees(a, b, func(key K, av, bv *V, inA, inB bool) {
+		switch {
+		case !inA:
+			changes = append(changes, Event[K, V]{Type: EventInsert, Key: key, Value: bv})
+		case !inB:
+			changes = append(changes, Event[K, V]{Type: EventRemove, Key: key, Old: av})
+		case !equalValues(av, bv, eq):
+			changes = append(changes, Event[K, V]{Type: EventUpdate, Key: key, Value: bv, Old: av})
+		}
+	})
+	return changes
+}
@@ -0,0 +1,37 @@
+package btree
+
+import (
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	a := treeOfKeys(1, 2, 3, 4, 5)
+	b := copyTree(a)
+	if changes := Diff(a, b, nil); len(changes) != 0 {
+		t.Errorf("expected no changes in a copy, found %v", changes)
+	}
+	_ = b.Remove(2)
+	v := "new"
+	_ = b.Add(4, &v)
+	_ = b.Add(6, &v)
+	eq := func(x, y *string) bool { return *x == *y }
+	changes := Diff(a, NewConcurrentBTree(b), eq)
+	expect := []Event[int, string]{
+		{Type: EventRemove, Key: 2, Old: a.Get(2)},
+		{Type: EventUpdate, Key: 4, Value: &v, Old: a.Get(4)},
+		{Type: EventInsert, Key: 6, Value: &v},
+	}
+	if len(changes) != len(expect) {
+		t.Fatalf("expected %d changes, found %d  %v", len(expect), len(changes), changes)
+	}
+	for i, c := range changes {
+		if c != expect[i] {
+			t.Errorf("expected change %v, found %v", expect[i], c)
+		}
+	}
+	_ = b.Add(1, &v)
+	_ = b.Add(1, a.Get(1))
+	if changes := Diff(a, b, eq); len(changes) != 3 {
+		t.Errorf("expected equal values to be unchanged, found %v", changes)
+	}
+}
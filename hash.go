@@ -0,0 +1,90 @@
+package btree
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/hex"
+)
+
+// Hash is a content hash of a tree, or a range of its keys.
+type Hash [sha256.Size]byte
+
+func (h Hash) String() string {
+	return hex.EncodeToString(h[:])
+}
+
+// add combines the given hash into this one.
+// Hashes are added, as four 64 bit numbers, so the order they are combined in makes no difference.
+func (h *Hash) add(o Hash) {
+	for i := 0; i < len(h); i += 8 {
+		binary.LittleEndian.PutUint64(h[i:], binary.LittleEndian.Uint64(h[i:])+binary.LittleEndian.Uint64(o[i:]))
+	}
+}
+
+// HashFunc hashes a single key and its value.
+type HashFunc[K cmp.Ordered, V any] func(key K, value *V) Hash
+
+// GobHash returns a HashFunc which hashes the gob encoding of the key and value with SHA-256.
+// Gob encodes maps in a random order, so a value holding a map, at any depth, hashes differently each time.
+// Such values need their own HashFunc, which writes the map entries in order.
+func GobHash[K cmp.Ordered, V any]() HashFunc[K, V] {
+	return func(key K, value *V) Hash {
+		buf := bytes.NewBuffer(nil)
+		enc := gob.NewEncoder(buf)
+		_ = enc.Encode(key)
+		_ = enc.Encode(value != nil)
+		if value != nil {
+			_ = enc.Encode(value)
+		}
+		return sha256.Sum256(buf.Bytes())
+	}
+}
+
+// HashedTree is a BTree which keeps a content hash of each node, for comparing replicas of a tree.
+// The hash of a node combines the hashes of its entries and its children, so trees with the same keys and values
+// have the same hashes, regardless of the order the keys were added in, or the shape of the trees.
+// Replicas can find the ranges of keys in which they differ, by comparing the hashes of smaller and smaller ranges.
+// Values must not be modified in place once added, only replaced with Add.
+type HashedTree[K cmp.Ordered, V any] interface {
+	BTree[K, V]
+	// RootHash returns the hash of the whole tree.
+	RootHash() Hash
+	// RangeHash returns the hash of the keys from lo to hi, inclusive.
+	RangeHash(lo, hi K) Hash
+}
+
+// hashedTree is an AggregateTree of the sum of the hashes of its entries.
+type hashedTree[K cmp.Ordered, V any] struct {
+	*aggregateTree[K, V, Hash]
+}
+
+// RootHash returns the hash of the whole tree.
+// Hashes are cleared along the path of each write, and recomputed here, so only changed nodes are hashed again.
+// As the computed hashes are stored in the nodes, it must not be called concurrently with any other use of the tree.
+func (t *hashedTree[K, V]) RootHash() Hash {
+	return t.Total()
+}
+
+// RangeHash returns the hash of the keys from lo to hi, inclusive.
+// The stored hashes of nodes lying entirely within the range are used, so only the nodes along the edges of the range are read.
+func (t *hashedTree[K, V]) RangeHash(lo, hi K) Hash {
+	return t.Aggregate(lo, hi)
+}
+
+// NewHashedBTree creates a new, empty, HashedTree of the given degree, hashing its entries with the given function.
+// If hash is nil, GobHash is used, which can not hash values holding maps.
+func NewHashedBTree[K cmp.Ordered, V any](degree int, hash HashFunc[K, V]) HashedTree[K, V] {
+	if hash == nil {
+		hash = GobHash[K, V]()
+	}
+	return &hashedTree[K, V]{newAggregateTree(degree, Monoid[K, V, Hash]{
+		Of: hash,
+		Combine: func(a, b Hash) Hash {
+			a.add(b)
+			return a
+		},
+	})}
+}
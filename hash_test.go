@@ -0,0 +1,113 @@
+package btree
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func hashedTreeOf(degree int, model map[int]string) HashedTree[int, string] {
+	ht := NewHashedBTree[int, string](degree, nil)
+	for k, v := range model {
+		v := v
+		_ = ht.Add(k, &v)
+	}
+	return ht
+}
+
+func TestHashedTree_RootHash(t *testing.T) {
+	model := map[int]string{}
+	for i := 0; i < 200; i++ {
+		model[i*3] = strconv.Itoa(i)
+	}
+	a, b := hashedTreeOf(3, model), hashedTreeOf(7, model)
+	if a.RootHash() != b.RootHash() {
+		t.Error("expected trees with the same contents to have the same hash")
+	}
+	if NewHashedBTree[int, string](3, nil).RootHash() != (Hash{}) {
+		t.Error("expected empty tree to have a zero hash")
+	}
+	v := "changed"
+	_ = b.Add(30, &v)
+	if a.RootHash() == b.RootHash() {
+		t.Error("expected changed tree to have a different hash")
+	}
+	_ = b.Remove(30)
+	_ = b.Add(30, &v)
+	_ = b.Add(30, a.Get(30))
+	if a.RootHash() != b.RootHash() {
+		t.Error("expected restored tree to have the same hash")
+	}
+}
+
+func TestHashedTree_Writes(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, degree := range []int{3, 4, 10} {
+		ht := NewHashedBTree[int, string](degree, nil)
+		model := map[int]string{}
+		for i := 0; i < 2000; i++ {
+			k := rnd.Intn(300)
+			v := strconv.Itoa(rnd.Intn(5))
+			switch rnd.Intn(6) {
+			case 0, 1:
+				_ = ht.Add(k, &v)
+				model[k] = v
+			case 2:
+				_ = ht.Remove(k)
+				delete(model, k)
+			case 3:
+				_ = ht.Update(k, func(old *string, exists bool) (*string, UpdateAction) {
+					if exists {
+						delete(model, k)
+						return nil, UpdateRemove
+					}
+					model[k] = v
+					return &v, UpdateSet
+				})
+			case 4:
+				var batch Batch[int, string]
+				for j := 0; j < 5; j++ {
+					k, v := k+j, v+strconv.Itoa(j)
+					batch.Add(k, &v)
+					model[k] = v
+				}
+				ht.Apply(&batch)
+			case 5:
+				ht.DeleteRange(k, k+5)
+				for j := k; j <= k+5; j++ {
+					delete(model, j)
+				}
+			}
+			if i%50 == 0 {
+				if ht.RootHash() != hashedTreeOf(3, model).RootHash() {
+					t.Fatalf("degree %d: hash differs from a new tree of the same contents after %d writes", degree, i)
+				}
+			}
+		}
+	}
+}
+
+func TestHashedTree_RangeHash(t *testing.T) {
+	model := map[int]string{}
+	for i := 0; i < 500; i++ {
+		model[i*2] = strconv.Itoa(i)
+	}
+	ht := hashedTreeOf(4, model)
+	rnd := rand.New(rand.NewSource(1))
+	for n := 0; n < 100; n++ {
+		lo := rnd.Intn(1100) - 50
+		hi := lo + rnd.Intn(500)
+		sub := map[int]string{}
+		for k, v := range model {
+			if k >= lo && k <= hi {
+				sub[k] = v
+			}
+		}
+		if ht.RangeHash(lo, hi) != hashedTreeOf(3, sub).RootHash() {
+			t.Fatalf("range hash from %d to %d differs from the hash of a tree of that range", lo, hi)
+		}
+	}
+	if ht.RangeHash(-1, 2000) != ht.RootHash() {
+		t.Error("expected range of all keys to have the root hash")
+	}
+}
@@ -0,0 +1,162 @@
+package btree
+
+import (
+	"cmp"
+	"fmt"
+)
+
+// Interval is the closed range of keys from Start to End, inclusive.
+type Interval[K cmp.Ordered] struct {
+	Start, End K
+}
+
+// Overlaps returns true if the interval shares any key with the given interval.
+func (iv Interval[K]) Overlaps(o Interval[K]) bool {
+	return !cmp.Less(iv.End, o.Start) && !cmp.Less(o.End, iv.Start)
+}
+
+// IntervalEntry is an interval, and its value, held in an IntervalTree.
+type IntervalEntry[K cmp.Ordered, V any] struct {
+	Interval Interval[K]
+	Value    *V
+}
+
+// IntervalTree is an index of intervals, for finding those overlapping a point or a range.
+// The intervals are held in a tree keyed by their start, in which each node keeps the greatest end of its intervals and those of its children,
+// so a query only descends into the nodes which may hold overlapping intervals.
+// An IntervalTree is not safe for concurrent use.
+type IntervalTree[K cmp.Ordered, V any] struct {
+	tree  *aggregateTree[K, intervalList[K, V], maxEnd[K]]
+	count int
+}
+
+// intervalList holds the intervals, and their values, with the same start.
+// Lists in the tree are never modified, only replaced, so the aggregates of the tree are kept up to date.
+type intervalList[K cmp.Ordered, V any] []intervalEnd[K, V]
+
+type intervalEnd[K cmp.Ordered, V any] struct {
+	end   K
+	value *V
+}
+
+// maxEnd is the greatest end of a group of intervals, when ok.
+type maxEnd[K cmp.Ordered] struct {
+	end K
+	ok  bool
+}
+
+func intervalMonoid[K cmp.Ordered, V any]() Monoid[K, intervalList[K, V], maxEnd[K]] {
+	return Monoid[K, intervalList[K, V], maxEnd[K]]{
+		Of: func(key K, list *intervalList[K, V]) maxEnd[K] {
+			var m maxEnd[K]
+			for _, ie := range *list {
+				if !m.ok || cmp.Less(m.end, ie.end) {
+					m = maxEnd[K]{end: ie.end, ok: true}
+				}
+			}
+			return m
+		},
+		Combine: func(a, b maxEnd[K]) maxEnd[K] {
+			if !a.ok || b.ok && cmp.Less(a.end, b.end) {
+				return b
+			}
+			return a
+		},
+	}
+}
+
+// Count returns the number of intervals in the tree.
+func (it *IntervalTree[K, V]) Count() int {
+	return it.count
+}
+
+// IsEmpty returns true if the tree holds no intervals.
+func (it *IntervalTree[K, V]) IsEmpty() bool {
+	return it.count == 0
+}
+
+// Insert adds the given interval and value to the tree. Any number of equal intervals may be added.
+func (it *IntervalTree[K, V]) Insert(iv Interval[K], value *V) error {
+	if cmp.Less(iv.End, iv.Start) {
+		return fmt.Errorf("interval end %v is before its start %v", iv.End, iv.Start)
+	}
+	var list intervalList[K, V]
+	if old := it.tree.Get(iv.Start); old != nil {
+		list = append(list, *old...)
+	}
+	list = append(list, intervalEnd[K, V]{end: iv.End, value: value})
+	it.count++
+	return it.tree.Add(iv.Start, &list)
+}
+
+// Delete removes the first interval equal to the given interval, with the given value, returning false if none is found.
+func (it *IntervalTree[K, V]) Delete(iv Interval[K], value *V) bool {
+	old := it.tree.Get(iv.Start)
+	if old == nil {
+		return false
+	}
+	for i, ie := range *old {
+		if ie.end != iv.End || ie.value != value {
+			continue
+		}
+		it.count--
+		if len(*old) == 1 {
+			_ = it.tree.Remove(iv.Start)
+			return true
+		}
+		list := intervalList[K, V](RemoveAtIndex(*old, i))
+		_ = it.tree.Add(iv.Start, &list)
+		return true
+	}
+	return false
+}
+
+// Stab returns every interval containing the given key, in order of their start.
+func (it *IntervalTree[K, V]) Stab(key K) []IntervalEntry[K, V] {
+	return it.Overlap(key, key)
+}
+
+// Overlap returns every interval sharing any key with the range from lo to hi, inclusive, in order of their start.
+func (it *IntervalTree[K, V]) Overlap(lo, hi K) []IntervalEntry[K, V] {
+	var found []IntervalEntry[K, V]
+	if !cmp.Less(hi, lo) {
+		it.overlap(it.tree.rootnode, lo, hi, &found)
+	}
+	return found
+}
+
+// overlap adds the intervals in the given node, and its children, which overlap the range, to found.
+// Returns false once an interval starting after the range is reached.
+func (it *IntervalTree[K, V]) overlap(nd *node[K, *intervalList[K, V]], lo, hi K, found *[]IntervalEntry[K, V]) bool {
+	for i := 0; i <= len(nd.Entries); i++ {
+		if !nd.IsLeaf() {
+			child := &nd.Children[i]
+			// skip children whose intervals all end before the range
+			if m := aggregateNode(child, it.tree.monoid); m.ok && !cmp.Less(m.end, lo) {
+				if !it.overlap(child, lo, hi, found) {
+					return false
+				}
+			}
+		}
+		if i == len(nd.Entries) {
+			break
+		}
+		e := nd.Entries[i]
+		if cmp.Less(hi, e.Key) {
+			return false
+		}
+		for _, ie := range *e.Value {
+			if !cmp.Less(ie.end, lo) {
+				*found = append(*found, IntervalEntry[K, V]{Interval: Interval[K]{Start: e.Key, End: ie.end}, Value: ie.value})
+			}
+		}
+	}
+	return true
+}
+
+// NewIntervalTree creates a new, empty, IntervalTree of the given degree.
+func NewIntervalTree[K cmp.Ordered, V any](degree int) *IntervalTree[K, V] {
+	return &IntervalTree[K, V]{
+		tree: newAggregateTree(degree, intervalMonoid[K, V]()),
+	}
+}
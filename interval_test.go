@@ -0,0 +1,93 @@
+package btree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestIntervalTree_Overlap(t *testing.T) {
+	it := NewIntervalTree[int, string](3)
+	names := []string{"a", "b", "c", "d", "e"}
+	intervals := []Interval[int]{{1, 5}, {3, 3}, {4, 10}, {8, 9}, {12, 20}}
+	for i, iv := range intervals {
+		if err := it.Insert(iv, &names[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := it.Insert(Interval[int]{5, 4}, nil); err == nil {
+		t.Error("expected error inserting an inverted interval")
+	}
+	for _, c := range []struct {
+		lo, hi int
+		expect string
+	}{
+		{3, 3, "ab"},
+		{4, 4, "ac"},
+		{6, 8, "cd"},
+		{10, 12, "ce"},
+		{21, 30, ""},
+		{0, 100, "abcde"},
+	} {
+		found := ""
+		for _, e := range it.Overlap(c.lo, c.hi) {
+			found += *e.Value
+		}
+		if found != c.expect {
+			t.Errorf("expected intervals %q overlapping %d to %d, found %q", c.expect, c.lo, c.hi, found)
+		}
+	}
+	if found := it.Stab(9); len(found) != 2 || *found[0].Value != "c" || found[1].Interval != (Interval[int]{8, 9}) {
+		t.Errorf("unexpected intervals containing %d %v", 9, found)
+	}
+	if !it.Delete(Interval[int]{4, 10}, &names[2]) || it.Delete(Interval[int]{4, 10}, &names[2]) {
+		t.Error("expected delete to report only known intervals")
+	}
+	if found := it.Stab(9); len(found) != 1 || it.Count() != 4 {
+		t.Errorf("expected deleted interval not found, found %v", found)
+	}
+}
+
+func TestIntervalTree_Random(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, degree := range []int{3, 4, 10} {
+		it := NewIntervalTree[int, int](degree)
+		var model []IntervalEntry[int, int]
+		for i := 0; i < 2000; i++ {
+			if len(model) > 0 && rnd.Intn(3) == 0 {
+				j := rnd.Intn(len(model))
+				if !it.Delete(model[j].Interval, model[j].Value) {
+					t.Fatalf("degree %d: expected interval %v deleted", degree, model[j].Interval)
+				}
+				model = append(model[:j], model[j+1:]...)
+			} else {
+				start := rnd.Intn(1000)
+				e := IntervalEntry[int, int]{Interval: Interval[int]{start, start + rnd.Intn(50)}, Value: new(int)}
+				_ = it.Insert(e.Interval, e.Value)
+				model = append(model, e)
+			}
+			if i%25 != 0 {
+				continue
+			}
+			q := Interval[int]{Start: rnd.Intn(1000)}
+			q.End = q.Start + rnd.Intn(30)
+			expect := 0
+			for _, e := range model {
+				if e.Interval.Overlaps(q) {
+					expect++
+				}
+			}
+			found := it.Overlap(q.Start, q.End)
+			if len(found) != expect {
+				t.Fatalf("degree %d: expected %d intervals overlapping %v, found %d", degree, expect, q, len(found))
+			}
+			for j, e := range found {
+				if !e.Interval.Overlaps(q) || j > 0 && found[j-1].Interval.Start > e.Interval.Start {
+					t.Fatalf("degree %d: unexpected interval %v overlapping %v", degree, e.Interval, q)
+				}
+			}
+		}
+		if it.Count() != len(model) {
+			t.Errorf("degree %d: expected %d intervals, found %d", degree, len(model), it.Count())
+		}
+	}
+}
@@ -0,0 +1,369 @@
+package btree
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+)
+
+// A mapped tree file is laid out as:
+//
+//	header:  magic (8 bytes), version (uint32), reserved (uint32), count (uint64), index offset (uint64)
+//	entries: for each entry, in key order:
+//	         key length (uvarint), key, has value (byte), value length (uvarint), value
+//	index:   count offsets (uint64), one for each entry, in key order
+//
+// All fixed size integers are little endian.
+// Keys are encoded so their byte order matches their key order, allowing the index to be binary searched
+// without decoding any keys.
+
+const mappedMagic = "BTREEMAP"
+
+const mappedVersion = 1
+
+const mappedHeaderSize = 32
+
+// Codec converts values to and from bytes, for storing in files.
+type Codec[V any] interface {
+	Encode(value *V) ([]byte, error)
+	Decode(data []byte) (*V, error)
+}
+
+type gobCodec[V any] struct{}
+
+func (c gobCodec[V]) Encode(value *V) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c gobCodec[V]) Decode(data []byte) (*V, error) {
+	v := new(V)
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// GobCodec returns a Codec which encodes values with encoding/gob.
+func GobCodec[V any]() Codec[V] {
+	return gobCodec[V]{}
+}
+
+// MappedTree is an immutable tree, read from a file written by WriteMappedTree.
+// The file is memory mapped, where the platform supports it, so opening it costs next to nothing
+// and its pages are shared by every process with the same file open.
+// A MappedTree is safe for concurrent use.
+type MappedTree[K cmp.Ordered, V any] struct {
+	data  []byte
+	index []byte
+	count int
+	codec Codec[V]
+}
+
+// Count returns the number of entries in the tree.
+func (m *MappedTree[K, V]) Count() int {
+	return m.count
+}
+
+// Get returns the value of the given key, or nil if the key is not in the tree.
+func (m *MappedTree[K, V]) Get(key K) (*V, error) {
+	kb := encodeKey(key)
+	i := m.search(kb)
+	if i >= m.count {
+		return nil, nil
+	}
+	ek, v, err := m.entry(i)
+	if err != nil || !bytes.Equal(ek, kb) {
+		return nil, err
+	}
+	return m.decodeValue(v)
+}
+
+// Keys returns all the keys in the tree, in order.
+func (m *MappedTree[K, V]) Keys(ctx context.Context) <-chan K {
+	ch := make(chan K)
+	go func(ch chan<- K) {
+		defer close(ch)
+		for i := 0; i < m.count; i++ {
+			kb, _, err := m.entry(i)
+			if err != nil {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case ch <- decodeKey[K](kb):
+			}
+		}
+	}(ch)
+	return ch
+}
+
+// Range calls fn, in key order, for every entry with a key from lo to hi, inclusive.
+// Iteration stops early if fn returns false.
+func (m *MappedTree[K, V]) Range(lo, hi K, fn func(key K, value *V) bool) error {
+	hb := encodeKey(hi)
+	for i := m.search(encodeKey(lo)); i < m.count; i++ {
+		kb, vb, err := m.entry(i)
+		if err != nil {
+			return err
+		}
+		if bytes.Compare(kb, hb) > 0 {
+			return nil
+		}
+		v, err := m.decodeValue(vb)
+		if err != nil {
+			return err
+		}
+		if !fn(decodeKey[K](kb), v) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// Close unmaps the file. The tree may not be used once closed.
+func (m *MappedTree[K, V]) Close() error {
+	data := m.data
+	m.data, m.index, m.count = nil, nil, 0
+	return unmapFile(data)
+}
+
+// search returns the index of the first entry with a key not less than the given encoded key.
+func (m *MappedTree[K, V]) search(kb []byte) int {
+	return sort.Search(m.count, func(i int) bool {
+		ek, _, err := m.entry(i)
+		return err != nil || bytes.Compare(ek, kb) >= 0
+	})
+}
+
+// entry returns the encoded key and value of the entry at the given index.
+func (m *MappedTree[K, V]) entry(i int) ([]byte, []byte, error) {
+	off := binary.LittleEndian.Uint64(m.index[i*8:])
+	if off < mappedHeaderSize || off >= uint64(len(m.data)) {
+		return nil, nil, fmt.Errorf("corrupt mapped tree, entry %d offset %d out of range", i, off)
+	}
+	b := m.data[off:]
+	kl, n := binary.Uvarint(b)
+	if n <= 0 || kl > uint64(len(b)-n) {
+		return nil, nil, fmt.Errorf("corrupt mapped tree, entry %d key out of range", i)
+	}
+	key := b[n : n+int(kl)]
+	return key, b[n+int(kl):], nil
+}
+
+// decodeValue decodes the value following an entries key.
+func (m *MappedTree[K, V]) decodeValue(b []byte) (*V, error) {
+	if len(b) == 0 {
+		return nil, errors.New("corrupt mapped tree, value missing")
+	}
+	if b[0] == 0 {
+		return nil, nil
+	}
+	vl, n := binary.Uvarint(b[1:])
+	if n <= 0 || vl > uint64(len(b)-1-n) {
+		return nil, errors.New("corrupt mapped tree, value out of range")
+	}
+	return m.codec.Decode(b[1+n : 1+n+int(vl)])
+}
+
+// WriteMappedTree writes the entries of the given tree to a file at the given path, to be opened with OpenMappedTree.
+// Values are encoded with the given codec.
+func WriteMappedTree[K cmp.Ordered, V any](path string, tree BTree[K, V], codec Codec[V]) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := writeMappedTree(f, tree, codec); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+func writeMappedTree[K cmp.Ordered, V any](f *os.File, tree BTree[K, V], codec Codec[V]) error {
+	w := bufio.NewWriter(f)
+	if _, err := w.Write(make([]byte, mappedHeaderSize)); err != nil {
+		return err
+	}
+	offset := uint64(mappedHeaderSize)
+	var offsets []uint64
+	var lb [binary.MaxVarintLen64]byte
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for k := range tree.Keys(ctx) {
+		offsets = append(offsets, offset)
+		var entry []byte
+		kb := encodeKey(k)
+		entry = append(entry, lb[:binary.PutUvarint(lb[:], uint64(len(kb)))]...)
+		entry = append(entry, kb...)
+		if v := tree.Get(k); v == nil {
+			entry = append(entry, 0)
+		} else {
+			vb, err := codec.Encode(v)
+			if err != nil {
+				return fmt.Errorf("failed to encode value of key %v  %w", k, err)
+			}
+			entry = append(entry, 1)
+			entry = append(entry, lb[:binary.PutUvarint(lb[:], uint64(len(vb)))]...)
+			entry = append(entry, vb...)
+		}
+		if _, err := w.Write(entry); err != nil {
+			return err
+		}
+		offset += uint64(len(entry))
+	}
+	// align the index to 8 bytes
+	pad := (8 - offset%8) % 8
+	if _, err := w.Write(make([]byte, pad)); err != nil {
+		return err
+	}
+	indexOffset := offset + pad
+	for _, off := range offsets {
+		if err := binary.Write(w, binary.LittleEndian, off); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	header := make([]byte, mappedHeaderSize)
+	copy(header, mappedMagic)
+	binary.LittleEndian.PutUint32(header[8:], mappedVersion)
+	binary.LittleEndian.PutUint64(header[16:], uint64(len(offsets)))
+	binary.LittleEndian.PutUint64(header[24:], indexOffset)
+	_, err := f.WriteAt(header, 0)
+	return err
+}
+
+// OpenMappedTree opens a file written by WriteMappedTree, decoding its values with the given codec.
+func OpenMappedTree[K cmp.Ordered, V any](path string, codec Codec[V]) (*MappedTree[K, V], error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.Size() < mappedHeaderSize {
+		return nil, fmt.Errorf("%s is not a mapped tree", path)
+	}
+	data, err := mapFile(f, int(fi.Size()))
+	if err != nil {
+		return nil, err
+	}
+	m, err := newMappedTree[K, V](data, codec)
+	if err != nil {
+		unmapFile(data)
+		return nil, fmt.Errorf("%s is not a valid mapped tree  %w", path, err)
+	}
+	return m, nil
+}
+
+func newMappedTree[K cmp.Ordered, V any](data []byte, codec Codec[V]) (*MappedTree[K, V], error) {
+	if string(data[:len(mappedMagic)]) != mappedMagic {
+		return nil, errors.New("unknown file type")
+	}
+	if v := binary.LittleEndian.Uint32(data[8:]); v != mappedVersion {
+		return nil, fmt.Errorf("unsupported version %d", v)
+	}
+	count := binary.LittleEndian.Uint64(data[16:])
+	indexOffset := binary.LittleEndian.Uint64(data[24:])
+	if indexOffset > uint64(len(data)) || count > (uint64(len(data))-indexOffset)/8 {
+		return nil, errors.New("index out of range")
+	}
+	return &MappedTree[K, V]{
+		data:  data,
+		index: data[indexOffset : indexOffset+count*8],
+		count: int(count),
+		codec: codec,
+	}, nil
+}
+
+// encodeKey encodes the given key so that the byte order of encoded keys matches the order of the keys.
+// Integers are encoded big endian, with the sign bit of signed integers inverted.
+// Floats have their sign bit inverted when positive, or all bits inverted when negative. NaN is encoded as zero.
+// Strings are their bytes.
+func encodeKey[K cmp.Ordered](key K) []byte {
+	rv := reflect.ValueOf(key)
+	var b [8]byte
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		binary.BigEndian.PutUint64(b[:], uint64(rv.Int())^(1<<63))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		binary.BigEndian.PutUint64(b[:], rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if math.IsNaN(f) {
+			break
+		}
+		if f == 0 {
+			// -0 and 0 are equal keys
+			f = 0
+		}
+		bits := math.Float64bits(f)
+		if bits&(1<<63) != 0 {
+			bits = ^bits
+		} else {
+			bits |= 1 << 63
+		}
+		binary.BigEndian.PutUint64(b[:], bits)
+	case reflect.String:
+		return []byte(rv.String())
+	default:
+		panic(fmt.Sprintf("unsupported key type %T", key))
+	}
+	return b[:]
+}
+
+// decodeKey decodes a key encoded by encodeKey.
+func decodeKey[K cmp.Ordered](b []byte) K {
+	var key K
+	rv := reflect.ValueOf(&key).Elem()
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		rv.SetInt(int64(binary.BigEndian.Uint64(b) ^ (1 << 63)))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		rv.SetUint(binary.BigEndian.Uint64(b))
+	case reflect.Float32, reflect.Float64:
+		bits := binary.BigEndian.Uint64(b)
+		switch {
+		case bits == 0:
+			rv.SetFloat(math.NaN())
+		case bits&(1<<63) != 0:
+			rv.SetFloat(math.Float64frombits(bits &^ (1 << 63)))
+		default:
+			rv.SetFloat(math.Float64frombits(^bits))
+		}
+	case reflect.String:
+		rv.SetString(string(b))
+	}
+	return key
+}
@@ -0,0 +1,146 @@
+package btree
+
+import (
+	"context"
+	"math"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestMappedTree_Get(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tree.map")
+	count := 1000
+	bt := createTestTree(10, count)
+	if err := bt.Add(count, nil); err != nil {
+		t.Error(err)
+	}
+	if err := WriteMappedTree[int, string](path, bt, GobCodec[string]()); err != nil {
+		t.Fatal(err)
+	}
+	mt, err := OpenMappedTree[int, string](path, GobCodec[string]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mt.Close()
+	if mt.Count() != count+1 {
+		t.Errorf("expected %d entries in mapped tree, found %d", count+1, mt.Count())
+	}
+	for i := 0; i < count; i++ {
+		v, err := mt.Get(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect := "-" + strconv.Itoa(i) + "-"
+		if v == nil || *v != expect {
+			t.Errorf("expected value %s for key %d, got %v", expect, i, v)
+		}
+	}
+	for _, k := range []int{-1, count + 1, math.MinInt, math.MaxInt} {
+		if v, err := mt.Get(k); v != nil || err != nil {
+			t.Errorf("expected nil for unknown key %d, got %v, %v", k, v, err)
+		}
+	}
+	if v, err := mt.Get(count); v != nil || err != nil {
+		t.Errorf("expected nil value for key %d, got %v, %v", count, v, err)
+	}
+
+	last := -1
+	found := 0
+	for k := range mt.Keys(context.Background()) {
+		if k <= last {
+			t.Errorf("unexpected key %d out of order, following %d", k, last)
+		}
+		last = k
+		found++
+	}
+	if found != count+1 {
+		t.Errorf("expected %d keys, found %d", count+1, found)
+	}
+}
+
+func TestMappedTree_Range(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tree.map")
+	bt := NewBTree[float64, string](4)
+	for _, k := range []float64{-10.5, -1, 0, 0.25, 3, 1e10, math.Inf(1), math.Inf(-1)} {
+		v := strconv.FormatFloat(k, 'g', -1, 64)
+		if err := bt.Add(k, &v); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := WriteMappedTree[float64, string](path, bt, GobCodec[string]()); err != nil {
+		t.Fatal(err)
+	}
+	mt, err := OpenMappedTree[float64, string](path, GobCodec[string]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mt.Close()
+
+	var keys []float64
+	err = mt.Range(-5, 5, func(key float64, value *string) bool {
+		if *value != strconv.FormatFloat(key, 'g', -1, 64) {
+			t.Errorf("unexpected value %s for key %v", *value, key)
+		}
+		keys = append(keys, key)
+		return true
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	expect := []float64{-1, 0, 0.25, 3}
+	if len(keys) != len(expect) {
+		t.Fatalf("expected keys %v in range, found %v", expect, keys)
+	}
+	for i := range expect {
+		if keys[i] != expect[i] {
+			t.Errorf("expected key %v at %d, found %v", expect[i], i, keys[i])
+		}
+	}
+
+	keys = nil
+	_ = mt.Range(0, 3, func(key float64, value *string) bool {
+		keys = append(keys, key)
+		return true
+	})
+	if len(keys) != 3 || keys[0] != 0 || keys[2] != 3 {
+		t.Errorf("expected keys %v, from lo to hi inclusive, found %v", []float64{0, 0.25, 3}, keys)
+	}
+
+	keys = nil
+	_ = mt.Range(math.Inf(-1), math.Inf(1), func(key float64, value *string) bool {
+		keys = append(keys, key)
+		return len(keys) < 2
+	})
+	if len(keys) != 2 || !math.IsInf(keys[0], -1) || keys[1] != -10.5 {
+		t.Errorf("expected range to stop after 2 keys, found %v", keys)
+	}
+}
+
+func TestMappedTree_StringKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tree.map")
+	bt := NewBTree[string, int](3)
+	for i := 0; i < 100; i++ {
+		v := i
+		if err := bt.Add(keyForInt(i), &v); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := WriteMappedTree[string, int](path, bt, GobCodec[int]()); err != nil {
+		t.Fatal(err)
+	}
+	mt, err := OpenMappedTree[string, int](path, GobCodec[int]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mt.Close()
+	for i := 0; i < 100; i++ {
+		v, err := mt.Get(keyForInt(i))
+		if err != nil || v == nil || *v != i {
+			t.Errorf("expected value %d for key %s, got %v, %v", i, keyForInt(i), v, err)
+		}
+	}
+	if _, err := OpenMappedTree[string, int](filepath.Join(t.TempDir(), "missing"), GobCodec[int]()); err == nil {
+		t.Error("Expected error opening missing file, got nil")
+	}
+}
@@ -0,0 +1,130 @@
+package btree
+
+import (
+	"cmp"
+	"unsafe"
+)
+
+// Sizer estimates the size, in bytes, of a value held in a tree, including any memory it refers to.
+type Sizer[V any] func(value *V) int64
+
+// memoryUsage counts the nodes, entries and value bytes of a tree, updated by each write.
+// S is the type stored in the entries of the tree, a pointer to the value in a BTree.
+type memoryUsage[S any] struct {
+	nodes, entries, values int64
+	sizer                  func(value S) int64
+}
+
+// written counts a value written to a key, replacing old when existed.
+func (u *memoryUsage[S]) written(old, value S, existed bool) {
+	if existed {
+		u.values -= u.sizer(old)
+	} else {
+		u.entries++
+	}
+	u.values += u.sizer(value)
+}
+
+// removed counts a key removed, with its value.
+func (u *memoryUsage[S]) removed(old S) {
+	u.entries--
+	u.values -= u.sizer(old)
+}
+
+// reset sets the counts to those of an empty tree, of a single empty node.
+func (u *memoryUsage[S]) reset() {
+	u.nodes, u.entries, u.values = 1, 0, 0
+}
+
+// add adds the counts of nodes moved into the tree.
+func (u *memoryUsage[S]) add(o *memoryUsage[S]) {
+	u.nodes += o.nodes
+	u.entries += o.entries
+	u.values += o.values
+}
+
+// sub removes the counts of nodes moved out of, or dropped from, the tree.
+func (u *memoryUsage[S]) sub(o *memoryUsage[S]) {
+	u.nodes -= o.nodes
+	u.entries -= o.entries
+	u.values -= o.values
+}
+
+func newMemoryUsage[V any](sizer Sizer[V]) *memoryUsage[*V] {
+	if sizer == nil {
+		sizer = shallowSize[V]
+	}
+	u := &memoryUsage[*V]{sizer: sizer}
+	u.reset()
+	return u
+}
+
+// shallowSize is the size of the value itself, without any memory it refers to.
+func shallowSize[V any](value *V) int64 {
+	if value == nil {
+		return 0
+	}
+	return int64(unsafe.Sizeof(*value))
+}
+
+// MemoryUsage returns the estimated bytes held by the nodes, entries and values of the tree.
+// The counts are kept by each write, including DeleteRange, SplitAt and Join, so it is cheap to call.
+// Values must not be modified in place once added, only replaced with Add, so their sizes are kept up to date.
+func (b *bTree[K, V]) MemoryUsage() int64 {
+	u := b.usage
+	return u.nodes*int64(unsafe.Sizeof(node[K, *V]{})) + u.entries*int64(unsafe.Sizeof(nodeEntry[K, *V]{})) + u.values
+}
+
+// countUsage adds this node, and its children, to the given counts.
+func (n *node[K, S]) countUsage(u *memoryUsage[S]) {
+	for stack := []*node[K, S]{n}; len(stack) > 0; {
+		stack = countNext(u, stack)
+	}
+}
+
+// countNext adds the last node of the stack to the given counts, returning the stack with its children in its place.
+func countNext[K cmp.Ordered, S any](u *memoryUsage[S], stack []*node[K, S]) []*node[K, S] {
+	n := stack[len(stack)-1]
+	stack = stack[:len(stack)-1]
+	u.nodes++
+	u.entries += int64(len(n.Entries))
+	for i := range n.Entries {
+		u.values += u.sizer(n.Entries[i].Value)
+	}
+	for i := range n.Children {
+		stack = append(stack, &n.Children[i])
+	}
+	return stack
+}
+
+// divideUsage returns the counts of each of two subtrees, which together hold the given counts.
+// Both are counted a node at a time, in turn, until the smaller is done, the other being what remains of the whole.
+func divideUsage[K cmp.Ordered, S any](u *memoryUsage[S], a, b *node[K, S]) (*memoryUsage[S], *memoryUsage[S]) {
+	ua, ub := &memoryUsage[S]{sizer: u.sizer}, &memoryUsage[S]{sizer: u.sizer}
+	sa, sb := []*node[K, S]{a}, []*node[K, S]{b}
+	for len(sa) > 0 && len(sb) > 0 {
+		sa = countNext(ua, sa)
+		sb = countNext(ub, sb)
+	}
+	rest := *u
+	if len(sa) == 0 {
+		rest.sub(ua)
+		return ua, &rest
+	}
+	rest.sub(ub)
+	return &rest, ub
+}
+
+// MemoryUsage returns the estimated bytes held by the nodes, keys and values of the tree.
+// The separator keys of the parent nodes are counted along with the pointers to their children.
+// Values must not be modified in place once added, only replaced with Add, so their sizes are kept up to date.
+func (b *bPlusTree[K, V]) MemoryUsage() int64 {
+	u := b.usage
+	var k K
+	var v *V
+	var child *bpNode[K, V]
+	entrySize := int64(unsafe.Sizeof(k) + unsafe.Sizeof(v))
+	// every node, but the root, has a pointer from its parent, and all but the first child of each parent a separator
+	childSize := int64(unsafe.Sizeof(child) + unsafe.Sizeof(k))
+	return u.nodes*int64(unsafe.Sizeof(bpNode[K, V]{})) + (u.nodes-1)*childSize + u.entries*entrySize + u.values
+}
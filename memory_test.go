@@ -0,0 +1,155 @@
+package btree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// usageCounts are the counts of a memoryUsage, without its sizer, so they can be compared.
+type usageCounts struct {
+	nodes, entries, values int64
+}
+
+// countedUsage returns the usage of the tree counted from its nodes, with the incremental counts it replaces.
+func countedUsage(b *bTree[int, string]) (counted, kept usageCounts) {
+	u := &memoryUsage[*string]{sizer: b.usage.sizer}
+	b.rootnode.countUsage(u)
+	return usageCounts{u.nodes, u.entries, u.values}, usageCounts{b.usage.nodes, b.usage.entries, b.usage.values}
+}
+
+func countBPNodes(nd *bpNode[int, string]) int64 {
+	n := int64(1)
+	for _, c := range nd.children {
+		n += countBPNodes(c)
+	}
+	return n
+}
+
+func TestMemoryUsage_Incremental(t *testing.T) {
+	sizer := func(value *string) int64 {
+		if value == nil {
+			return 0
+		}
+		return int64(len(*value))
+	}
+	rnd := rand.New(rand.NewSource(1))
+	for _, degree := range []int{3, 4, 10} {
+		b := NewBTreeWithSizer[int, string](degree, sizer).(*bTree[int, string])
+		bp := NewBPlusTreeWithSizer[int, string](degree, sizer).(*bPlusTree[int, string])
+		values := 0
+		for i := 0; i < 5000; i++ {
+			k := rnd.Intn(500)
+			v := string(make([]byte, rnd.Intn(20)))
+			switch rnd.Intn(4) {
+			case 0:
+				_ = b.Remove(k)
+				_ = bp.Remove(k)
+			case 1:
+				_ = b.Update(k, func(old *string, exists bool) (*string, UpdateAction) {
+					return &v, UpdateSet
+				})
+				_ = bp.Update(k, func(old *string, exists bool) (*string, UpdateAction) {
+					return &v, UpdateSet
+				})
+			case 2:
+				batch := &Batch[int, string]{}
+				batch.Add(k, &v)
+				batch.Remove(k + 1)
+				b.Apply(batch)
+				bp.Apply(batch)
+			default:
+				_ = b.Add(k, &v)
+				_ = bp.Add(k, &v)
+			}
+			if i%100 != 0 {
+				continue
+			}
+			counted, kept := countedUsage(b)
+			if counted != kept {
+				t.Fatalf("degree %d: expected usage %+v, kept %+v", degree, counted, kept)
+			}
+			if kept.entries != int64(b.Count()) {
+				t.Fatalf("degree %d: expected %d entries, kept %d", degree, b.Count(), kept.entries)
+			}
+			if n := countBPNodes(bp.root); bp.usage.nodes != n || bp.usage.entries != int64(bp.count) || bp.usage.values != kept.values {
+				t.Fatalf("degree %d: expected B+ tree usage of %d nodes, %d entries, %d bytes, kept %+v", degree, n, bp.count, kept.values, *bp.usage)
+			}
+			values = int(kept.values)
+		}
+		if b.MemoryUsage() <= int64(values) || bp.MemoryUsage() <= int64(values) {
+			t.Errorf("degree %d: expected usage greater than the values alone", degree)
+		}
+	}
+}
+
+func TestMemoryUsage_Structural(t *testing.T) {
+	b := newBTree[int, string](4)
+	empty := b.MemoryUsage()
+	fillTree(b, 1000)
+	full := b.MemoryUsage()
+	if full <= empty {
+		t.Fatalf("expected usage to grow from %d, found %d", empty, full)
+	}
+	b.DeleteRange(100, 199)
+	if counted, kept := countedUsage(b); counted != kept || counted.entries != 900 || b.MemoryUsage() >= full {
+		t.Errorf("expected usage of 900 entries after delete range, found %+v, counted %+v", kept, counted)
+	}
+	left, right, err := b.SplitAt(500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.MemoryUsage() != empty {
+		t.Errorf("expected split tree to be empty, found usage %d", b.MemoryUsage())
+	}
+	l, r := left.(*bTree[int, string]), right.(*bTree[int, string])
+	lc, lk := countedUsage(l)
+	rc, rk := countedUsage(r)
+	if lc != lk || rc != rk || lc.entries != 400 || rc.entries != 500 {
+		t.Errorf("expected usage of 400 and 500 entries in the halves, found %+v and %+v", lk, rk)
+	}
+	joined, err := Join[int, string](left, right)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = joined.Add(100, new(string))
+	if counted, kept := countedUsage(joined.(*bTree[int, string])); counted != kept || counted.entries != 901 {
+		t.Errorf("expected usage of 901 entries after join, found %+v, counted %+v", kept, counted)
+	}
+}
+
+func TestMemoryUsage_SplitJoin_Random(t *testing.T) {
+	sizer := func(value *string) int64 {
+		return int64(len(*value))
+	}
+	rnd := rand.New(rand.NewSource(1))
+	for _, degree := range []int{3, 4, 5, 10} {
+		for n := 0; n < 100; n++ {
+			bt := NewBTreeWithSizer[int, string](degree, sizer)
+			for i := rnd.Intn(500); i > 0; i-- {
+				v := string(make([]byte, rnd.Intn(20)))
+				_ = bt.Add(rnd.Intn(1000), &v)
+			}
+			lo := rnd.Intn(1000)
+			bt.DeleteRange(lo, lo+rnd.Intn(200))
+			if counted, kept := countedUsage(bt.(*bTree[int, string])); counted != kept {
+				t.Fatalf("degree %d: expected usage %+v after delete range, kept %+v", degree, counted, kept)
+			}
+			left, right, err := bt.SplitAt(rnd.Intn(1000))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, half := range []BTree[int, string]{left, right} {
+				if counted, kept := countedUsage(half.(*bTree[int, string])); counted != kept {
+					t.Fatalf("degree %d: expected usage %+v after split, kept %+v", degree, counted, kept)
+				}
+			}
+			joined, err := Join(left, right)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if counted, kept := countedUsage(joined.(*bTree[int, string])); counted != kept {
+				t.Fatalf("degree %d: expected usage %+v after join, kept %+v", degree, counted, kept)
+			}
+		}
+	}
+}
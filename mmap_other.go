@@ -0,0 +1,21 @@
+//go:build !unix
+
+package btree
+
+import (
+	"io"
+	"os"
+)
+
+// mapFile reads the first size bytes of the given file into memory, on platforms without mmap.
+func mapFile(f *os.File, size int) ([]byte, error) {
+	data := make([]byte, size)
+	if _, err := io.ReadFull(f, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func unmapFile(data []byte) error {
+	return nil
+}
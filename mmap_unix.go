@@ -0,0 +1,20 @@
+//go:build unix
+
+package btree
+
+import (
+	"os"
+	"syscall"
+)
+
+// mapFile memory maps the first size bytes of the given file, read only.
+func mapFile(f *os.File, size int) ([]byte, error) {
+	return syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+func unmapFile(data []byte) error {
+	if data == nil {
+		return nil
+	}
+	return syscall.Munmap(data)
+}
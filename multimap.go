@@ -0,0 +1,131 @@
+package btree
+
+import (
+	"cmp"
+	"context"
+	"sort"
+)
+
+// Multimap is an ordered map holding any number of values for each key.
+// All the values of a key are held in a single entry of the tree, so values of equal keys are never divided between nodes,
+// and splitting or merging nodes moves them together.
+// Values of a key are kept in the order they were added, or ordered by a comparator, when one is given.
+// A Multimap is not safe for concurrent use.
+type Multimap[K cmp.Ordered, V any] struct {
+	tree    *bTree[K, multiValues[V]]
+	compare func(a, b *V) int
+	count   int
+}
+
+// multiValues are the values of a single key.
+type multiValues[V any] []*V
+
+// Degree returns the degree of the tree holding the keys.
+func (m *Multimap[K, V]) Degree() int {
+	return m.tree.Degree()
+}
+
+// IsEmpty returns true if the multimap has no keys.
+func (m *Multimap[K, V]) IsEmpty() bool {
+	return m.tree.IsEmpty()
+}
+
+// Count returns the number of values, of all keys.
+func (m *Multimap[K, V]) Count() int {
+	return m.count
+}
+
+// KeyCount returns the number of distinct keys.
+func (m *Multimap[K, V]) KeyCount() int {
+	return m.tree.Count()
+}
+
+// Keys returns each distinct key, in order.
+func (m *Multimap[K, V]) Keys(ctx context.Context) <-chan K {
+	return m.tree.Keys(ctx)
+}
+
+// Add adds the given value to the values of the key.
+func (m *Multimap[K, V]) Add(key K, value *V) {
+	m.count++
+	if ne := m.tree.rootnode.Get(key); ne != nil {
+		values := *ne.Value
+		i := len(values)
+		if m.compare != nil {
+			// after any equal values, so equal values remain in the order they were added
+			i = sort.Search(len(values), func(i int) bool {
+				return m.compare(values[i], value) > 0
+			})
+		}
+		*ne.Value = InsertAtIndex(value, values, i)
+		return
+	}
+	_ = m.tree.Add(key, &multiValues[V]{value})
+}
+
+// Get returns the first value of the given key, or nil if the key is unknown.
+func (m *Multimap[K, V]) Get(key K) *V {
+	if values := m.tree.Get(key); values != nil {
+		return (*values)[0]
+	}
+	return nil
+}
+
+// GetAll returns all the values of the given key, in order.
+func (m *Multimap[K, V]) GetAll(key K) []*V {
+	values := m.tree.Get(key)
+	if values == nil {
+		return nil
+	}
+	return append([]*V{}, *values...)
+}
+
+// RemoveOne removes the given value, the same pointer, from the values of the key, returning false if it is not found.
+// Values which the comparator orders equally are not the same value, so are not removed.
+func (m *Multimap[K, V]) RemoveOne(key K, value *V) bool {
+	return m.RemoveEqual(key, value, func(a, b *V) bool {
+		return a == b
+	})
+}
+
+// RemoveEqual removes the first value of the key which eq finds equal to the given value, returning false if none is found.
+func (m *Multimap[K, V]) RemoveEqual(key K, value *V, eq func(a, b *V) bool) bool {
+	ne := m.tree.rootnode.Get(key)
+	if ne == nil {
+		return false
+	}
+	values := *ne.Value
+	for i, v := range values {
+		if eq(v, value) {
+			m.count--
+			if len(values) == 1 {
+				_ = m.tree.Remove(key)
+			} else {
+				*ne.Value = RemoveAtIndex(values, i)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveAll removes the key and all its values, returning the number of values removed.
+func (m *Multimap[K, V]) RemoveAll(key K) int {
+	values := m.tree.Get(key)
+	if values == nil {
+		return 0
+	}
+	n := len(*values)
+	_ = m.tree.Remove(key)
+	m.count -= n
+	return n
+}
+
+// NewMultimap creates a new, empty, Multimap of the given degree.
+// If compare is not nil, the values of each key are kept in the order it gives them, otherwise in the order they are added.
+func NewMultimap[K cmp.Ordered, V any](degree int, compare func(a, b *V) int) *Multimap[K, V] {
+	return &Multimap[K, V]{
+		tree:    newBTree[K, multiValues[V]](degree),
+		compare: compare,
+	}
+}
@@ -0,0 +1,124 @@
+package btree
+
+import (
+	"cmp"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func multimapValues(values []*string) []string {
+	var s []string
+	for _, v := range values {
+		s = append(s, *v)
+	}
+	return s
+}
+
+func TestMultimap_InsertionOrder(t *testing.T) {
+	mm := NewMultimap[int, string](3, nil)
+	values := []string{"c", "a", "b", "a"}
+	for i := range values {
+		mm.Add(1, &values[i])
+	}
+	mm.Add(2, &values[0])
+	if found := multimapValues(mm.GetAll(1)); !slices.Equal(found, values) {
+		t.Errorf("expected values %v, found %v", values, found)
+	}
+	if mm.Count() != 5 || mm.KeyCount() != 2 {
+		t.Errorf("expected %d values of %d keys, found %d of %d", 5, 2, mm.Count(), mm.KeyCount())
+	}
+	a := "a"
+	if mm.RemoveOne(1, &a) {
+		t.Error("expected no remove of a different pointer without a comparator")
+	}
+	if !mm.RemoveOne(1, &values[3]) || !slices.Equal(multimapValues(mm.GetAll(1)), []string{"c", "a", "b"}) {
+		t.Errorf("expected one value removed, found %v", multimapValues(mm.GetAll(1)))
+	}
+	if n := mm.RemoveAll(1); n != 3 || mm.GetAll(1) != nil || mm.Count() != 1 {
+		t.Errorf("expected %d values removed, removed %d", 3, n)
+	}
+	if !mm.RemoveOne(2, &values[0]) || !mm.IsEmpty() {
+		t.Error("expected last value removed to remove the key")
+	}
+}
+
+func TestMultimap_Comparator(t *testing.T) {
+	mm := NewMultimap[int, string](3, func(a, b *string) int {
+		return cmp.Compare(strings.ToLower(*a), strings.ToLower(*b))
+	})
+	values := []string{"c", "A", "b", "a"}
+	for i := range values {
+		mm.Add(1, &values[i])
+	}
+	if found, expect := multimapValues(mm.GetAll(1)), []string{"A", "a", "b", "c"}; !slices.Equal(found, expect) {
+		t.Errorf("expected values %v, found %v", expect, found)
+	}
+	a := "a"
+	if mm.RemoveOne(1, &a) {
+		t.Error("expected value ordered equally, but not the same value, not removed")
+	}
+	equalFold := func(a, b *string) bool {
+		return strings.EqualFold(*a, *b)
+	}
+	if !mm.RemoveEqual(1, &a, equalFold) || *mm.Get(1) != "a" {
+		t.Errorf("expected first equal value removed, found %v", multimapValues(mm.GetAll(1)))
+	}
+}
+
+func TestMultimap_RemoveOneSameValue(t *testing.T) {
+	type event struct {
+		priority int
+		name     string
+	}
+	mm := NewMultimap[int, event](3, func(a, b *event) int {
+		return cmp.Compare(a.priority, b.priority)
+	})
+	a, b := event{1, "a"}, event{1, "b"}
+	mm.Add(1, &a)
+	mm.Add(1, &b)
+	if !mm.RemoveOne(1, &b) {
+		t.Fatal("expected value removed")
+	}
+	if values := mm.GetAll(1); len(values) != 1 || values[0] != &a {
+		t.Errorf("expected only the given value removed, found %v", values)
+	}
+}
+
+func TestMultimap_Random(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	mm := NewMultimap[int, int](3, nil)
+	model := map[int][]*int{}
+	count := 0
+	for i := 0; i < 20000; i++ {
+		k := rnd.Intn(100)
+		switch rnd.Intn(4) {
+		case 0, 1:
+			v := i
+			mm.Add(k, &v)
+			model[k] = append(model[k], &v)
+			count++
+		case 2:
+			if vs := model[k]; len(vs) > 0 {
+				j := rnd.Intn(len(vs))
+				if !mm.RemoveOne(k, vs[j]) {
+					t.Fatalf("expected value of key %d removed", k)
+				}
+				model[k] = slices.Delete(vs, j, j+1)
+				count--
+			}
+		case 3:
+			count -= mm.RemoveAll(k)
+			delete(model, k)
+		}
+	}
+	for k := 0; k < 100; k++ {
+		if !slices.Equal(mm.GetAll(k), model[k]) {
+			t.Fatalf("unexpected values of key %d", k)
+		}
+	}
+	if mm.Count() != count {
+		t.Errorf("expected %d values, found %d", count, mm.Count())
+	}
+}
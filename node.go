@@ -7,9 +7,11 @@ import (
 )
 
 // nodeEntry represents the container for each Key entry in the Node.
-type nodeEntry[K cmp.Ordered, V any] struct {
+// Value is of the type stored by the tree, a pointer to the value in a BTree, or the value itself in a ValueTree.
+// Value comes first, as Go pads a zero sized field at the end of a struct, so an entry of an empty Value is only its Key.
+type nodeEntry[K cmp.Ordered, S any] struct {
+	Value S
 	Key   K
-	Value *V
 }
 
 // node is the container of ordered NodeEntries.
@@ -17,22 +19,25 @@ type nodeEntry[K cmp.Ordered, V any] struct {
 // node may have child nodes.  When present, number of child nodes must be equal to the
 // number of entries, plus one.
 // When no child nodes present, node is known as a leaf node. #IsLeaf returns true.
-type node[K cmp.Ordered, V any] struct {
-	Entries  []nodeEntry[K, V]
-	Children []node[K, V]
+// S is the type of the values stored in its entries.
+type node[K cmp.Ordered, S any] struct {
+	Entries  []nodeEntry[K, S]
+	Children []node[K, S]
+	// agg is the aggregate of the node and its children, of a tree with a Monoid, nil until computed, or when changed since.
+	agg any
 }
 
-func (n node[K, V]) IsLeaf() bool {
+func (n node[K, S]) IsLeaf() bool {
 	return len(n.Children) == 0
 }
 
-func (n node[K, V]) IsEmpty() bool {
+func (n node[K, S]) IsEmpty() bool {
 	return n.IsLeaf() && len(n.Entries) == 0
 }
 
 // Get returns the nodeEntry for the given key if it is present in the node or its children.
 // If the key is not found, nil is returned.
-func (n *node[K, V]) Get(key K) *nodeEntry[K, V] {
+func (n *node[K, S]) Get(key K) *nodeEntry[K, S] {
 	i, e := n.keyIndex(key)
 	if e != nil {
 		return e
@@ -46,9 +51,9 @@ func (n *node[K, V]) Get(key K) *nodeEntry[K, V] {
 	return n.Children[i].Get(key)
 }
 
-// Insert the given key/value pair into this leaf node.
+// Insert the given key/value pair into this leaf node, returning the previous value and true, if the key existed.
 // If node is not a leaf node panics.
-func (n *node[K, V]) Insert(key K, value *V) {
+func (n *node[K, S]) Insert(key K, value S) (S, bool) {
 	if !n.IsLeaf() {
 		log.Panicf("Can not insert %v into a non leaf node", key)
 	}
@@ -57,65 +62,84 @@ func (n *node[K, V]) Insert(key K, value *V) {
 		// no existing key > new key, append to the end
 		i = len(n.Entries)
 	}
-	if e == nil {
-		n.Entries = InsertAtIndex(nodeEntry[K, V]{}, n.Entries, i)
+	existed := e != nil
+	if !existed {
+		n.Entries = InsertAtIndex(nodeEntry[K, S]{}, n.Entries, i)
 		e = &n.Entries[i]
 	}
+	old := e.Value
 	e.Key = key
 	e.Value = value
+	return old, existed
 }
 
-// Delete the given key from this node
-func (n *node[K, V]) Delete(key K) error {
+// Delete the given key from this node, returning the value it held.
+func (n *node[K, S]) Delete(key K) (S, error) {
 	i, e := n.keyIndex(key)
 	if e == nil {
-		return fmt.Errorf("key %v is unknown", key)
+		var zero S
+		return zero, fmt.Errorf("key %v is unknown", key)
 	}
+	old := e.Value
 	n.Entries = RemoveAtIndex(n.Entries, i)
-	return nil
+	return old, nil
 }
 
 // Split this node into two child nodes with the median entry a single entry parent node.
-func (n *node[K, V]) Split() *node[K, V] {
+func (n *node[K, S]) Split() *node[K, S] {
 	l := len(n.Entries)
 	if l < 3 {
 		log.Fatalf("node too small to split. onlt %d entries found", len(n.Entries))
 	}
 	m := l / 2
-	child1 := &node[K, V]{Entries: n.Entries[:m]}
-	child2 := &node[K, V]{Entries: n.Entries[m+1:]}
+	// copy the entries, so the children do not share (and append over) each others backing arrays
+	child1 := &node[K, S]{Entries: append([]nodeEntry[K, S]{}, n.Entries[:m]...)}
+	child2 := &node[K, S]{Entries: append([]nodeEntry[K, S]{}, n.Entries[m+1:]...)}
 	if !n.IsLeaf() {
 		child1.Children = append(child1.Children, n.Children[:m+1]...)
 		child2.Children = append(child2.Children, n.Children[m+1:]...)
 	}
-	return &node[K, V]{
-		Entries:  []nodeEntry[K, V]{n.Entries[m]},
-		Children: []node[K, V]{*child1, *child2},
+	return &node[K, S]{
+		Entries:  []nodeEntry[K, S]{n.Entries[m]},
+		Children: []node[K, S]{*child1, *child2},
 	}
 }
 
-func (n node[K, V]) LastEntry() *nodeEntry[K, V] {
+func (n node[K, S]) LastEntry() *nodeEntry[K, S] {
 	if len(n.Entries) == 0 {
 		return nil
 	}
 	return &n.Entries[len(n.Entries)-1]
 }
 
-func (n node[K, V]) LastChild() *node[K, V] {
+func (n node[K, S]) LastChild() *node[K, S] {
 	if n.IsLeaf() {
 		return nil
 	}
 	return &n.Children[len(n.Children)-1]
 }
 
-func (n node[K, V]) String() string {
+// clone returns a deep copy of this node and its children.
+// The entry values are shared with this node.
+func (n node[K, S]) clone() *node[K, S] {
+	nn := &node[K, S]{Entries: append([]nodeEntry[K, S]{}, n.Entries...)}
+	if !n.IsLeaf() {
+		nn.Children = make([]node[K, S], len(n.Children))
+		for i := range n.Children {
+			nn.Children[i] = *n.Children[i].clone()
+		}
+	}
+	return nn
+}
+
+func (n node[K, S]) String() string {
 	if len(n.Children) > 0 {
 		return fmt.Sprintf("{Entries: %v, Children: %v}", n.Entries, n.Children)
 	}
 	return fmt.Sprintf("{Entries: %v, Leaf}", n.Entries)
 }
 
-func (n *node[K, V]) mergeChild(childIndex int) int {
+func (n *node[K, S]) mergeChild(childIndex int) int {
 	entryIndex := childIndex
 	if childIndex > 0 {
 		// not the first child, perform backmerge
@@ -128,10 +152,11 @@ func (n *node[K, V]) mergeChild(childIndex int) int {
 	// remove entry, now merged into child and also remove now empty child.
 	n.Entries = RemoveAtIndex(n.Entries, entryIndex)
 	n.Children = RemoveAtIndex(n.Children, childIndex)
+	n.Children[entryIndex].agg = nil
 	return entryIndex
 }
 
-func (n *node[K, V]) forwardMergeChild(childIndex int) {
+func (n *node[K, S]) forwardMergeChild(childIndex int) {
 	peer := &n.Children[childIndex+1]
 	child := &n.Children[childIndex]
 	// Add parent entry to end of child entries before added peers entries
@@ -143,7 +168,7 @@ func (n *node[K, V]) forwardMergeChild(childIndex int) {
 	}
 }
 
-func (n *node[K, V]) backMergeChild(childIndex int) {
+func (n *node[K, S]) backMergeChild(childIndex int) {
 	peer := &n.Children[childIndex-1]
 	child := &n.Children[childIndex]
 	// add parent entry to end of (back) peer before adding child entries
@@ -154,22 +179,46 @@ func (n *node[K, V]) backMergeChild(childIndex int) {
 	}
 }
 
-func (n *node[K, V]) getPreceeedingNode(nd *node[K, V]) *node[K, V] {
+func (n *node[K, S]) getPreceeedingNode(nd *node[K, S]) *node[K, S] {
 	for !nd.IsLeaf() {
 		nd = nd.LastChild()
 	}
 	return nd
 }
 
+// ascend calls fn with each entry of this node and its children, in key order, until fn returns false.
+// When from is not nil, only entries with keys greater than from, or equal to it when inclusive, are given to fn.
+// Returns false if fn stopped the iteration.
+func (n *node[K, S]) ascend(from *K, inclusive bool, fn func(e *nodeEntry[K, S]) bool) bool {
+	i := 0
+	if from != nil {
+		for ; i < len(n.Entries); i++ {
+			c := cmp.Compare(n.Entries[i].Key, *from)
+			if c > 0 || inclusive && c == 0 {
+				break
+			}
+		}
+	}
+	for ; i <= len(n.Entries); i++ {
+		if !n.IsLeaf() && !n.Children[i].ascend(from, inclusive, fn) {
+			return false
+		}
+		if i < len(n.Entries) && !fn(&n.Entries[i]) {
+			return false
+		}
+	}
+	return true
+}
+
 // keyIndex searches the nodes Entries for a matching key.
 // If the key is found, the index in the Entries slice and the Entry iteself are returned.
 // If the key is not found, but a key in this node is greater than the given key, tha index of the larger key is returned with a nil nodeEntry.
 // If the given key is not in the Entries AND greater than all those keys, -1 and nil are returned.
-func (n *node[K, V]) keyIndex(key K) (int, *nodeEntry[K, V]) {
-	for i, entry := range n.Entries {
-		c := cmp.Compare(key, entry.Key)
+func (n *node[K, S]) keyIndex(key K) (int, *nodeEntry[K, S]) {
+	for i := range n.Entries {
+		c := cmp.Compare(key, n.Entries[i].Key)
 		if c == 0 {
-			return i, &entry
+			return i, &n.Entries[i]
 		}
 		if c < 0 {
 			return i, nil
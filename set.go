@@ -0,0 +1,116 @@
+package btree
+
+import (
+	"cmp"
+	"context"
+)
+
+// BTreeSet is an ordered set of keys.
+// It is balanced in the same way as a BTree, but its nodes hold only keys, without a value for each.
+type BTreeSet[K cmp.Ordered] interface {
+	Degree() int
+	IsEmpty() bool
+	Count() int
+	// Insert adds the given key, returning false if it was already in the set.
+	Insert(key K) bool
+	Contains(key K) bool
+	// Delete removes the given key, returning false if it was not in the set.
+	Delete(key K) bool
+	Keys(ctx context.Context) <-chan K
+	// Range calls fn with each key from lo to hi, inclusive, in order, until fn returns false.
+	Range(lo, hi K, fn func(key K) bool)
+	// Union, Intersect, Difference and SymmetricDifference return a new set, of the same degree as this one.
+	Union(other BTreeSet[K]) BTreeSet[K]
+	Intersect(other BTreeSet[K]) BTreeSet[K]
+	Difference(other BTreeSet[K]) BTreeSet[K]
+	SymmetricDifference(other BTreeSet[K]) BTreeSet[K]
+}
+
+// bTreeSet is a ValueTree of keys with no values.
+// The values are empty structs, which take no space in an entry, so its nodes hold only keys.
+type bTreeSet[K cmp.Ordered] struct {
+	*valueTree[K, struct{}]
+}
+
+func (s *bTreeSet[K]) Insert(key K) bool {
+	return s.set(key, struct{}{})
+}
+
+func (s *bTreeSet[K]) Contains(key K) bool {
+	_, ok := s.Get(key)
+	return ok
+}
+
+func (s *bTreeSet[K]) Range(lo, hi K, fn func(key K) bool) {
+	s.valueTree.Range(lo, hi, func(key K, _ struct{}) bool {
+		return fn(key)
+	})
+}
+
+func (s *bTreeSet[K]) Union(other BTreeSet[K]) BTreeSet[K] {
+	return s.merge(other, func(inS, inOther bool) bool {
+		return true
+	})
+}
+
+func (s *bTreeSet[K]) Intersect(other BTreeSet[K]) BTreeSet[K] {
+	return s.merge(other, func(inS, inOther bool) bool {
+		return inS && inOther
+	})
+}
+
+func (s *bTreeSet[K]) Difference(other BTreeSet[K]) BTreeSet[K] {
+	return s.merge(other, func(inS, inOther bool) bool {
+		return inS && !inOther
+	})
+}
+
+func (s *bTreeSet[K]) SymmetricDifference(other BTreeSet[K]) BTreeSet[K] {
+	return s.merge(other, func(inS, inOther bool) bool {
+		return inS != inOther
+	})
+}
+
+// merge walks the keys of both sets together, in order, inserting into a new set each key for which keep returns true.
+func (s *bTreeSet[K]) merge(other BTreeSet[K], keep func(inS, inOther bool) bool) BTreeSet[K] {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	result := newBTreeSet[K](s.degree)
+	a, b := s.Keys(ctx), other.Keys(ctx)
+	ak, aok := <-a
+	bk, bok := <-b
+	for aok || bok {
+		c := 0
+		switch {
+		case !bok:
+			c = -1
+		case !aok:
+			c = 1
+		default:
+			c = cmp.Compare(ak, bk)
+		}
+		key := ak
+		if c > 0 {
+			key = bk
+		}
+		if keep(c <= 0, c >= 0) {
+			result.Insert(key)
+		}
+		if c <= 0 {
+			ak, aok = <-a
+		}
+		if c >= 0 {
+			bk, bok = <-b
+		}
+	}
+	return result
+}
+
+// NewBTreeSet creates a new, empty, set of the given degree.
+func NewBTreeSet[K cmp.Ordered](degree int) BTreeSet[K] {
+	return newBTreeSet[K](degree)
+}
+
+func newBTreeSet[K cmp.Ordered](degree int) *bTreeSet[K] {
+	return &bTreeSet[K]{newValueTree[K, struct{}](degree)}
+}
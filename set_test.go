@@ -0,0 +1,122 @@
+package btree
+
+import (
+	"context"
+	"math/rand"
+	"slices"
+	"testing"
+	"unsafe"
+)
+
+func setOf(keys ...int) BTreeSet[int] {
+	s := NewBTreeSet[int](3)
+	for _, k := range keys {
+		s.Insert(k)
+	}
+	return s
+}
+
+func setKeys(s BTreeSet[int]) []int {
+	var keys []int
+	for k := range s.Keys(context.Background()) {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func TestBTreeSet_InsertDelete(t *testing.T) {
+	s := NewBTreeSet[int](3)
+	if !s.Insert(5) || s.Insert(5) {
+		t.Error("expected insert to report only new keys")
+	}
+	if !s.Contains(5) || s.Contains(6) {
+		t.Error("unexpected result from Contains")
+	}
+	if !s.Delete(5) || s.Delete(5) {
+		t.Error("expected delete to report only known keys")
+	}
+	if !s.IsEmpty() {
+		t.Error("expected empty set")
+	}
+}
+
+func TestBTreeSet_Random(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, degree := range []int{3, 4, 5, 10} {
+		s := NewBTreeSet[int](degree)
+		model := map[int]bool{}
+		for i := 0; i < 10000; i++ {
+			k := rnd.Intn(500)
+			if rnd.Intn(2) == 0 {
+				if s.Insert(k) == model[k] {
+					t.Fatalf("degree %d: unexpected insert result for key %d", degree, k)
+				}
+				model[k] = true
+			} else {
+				if s.Delete(k) != model[k] {
+					t.Fatalf("degree %d: unexpected delete result for key %d", degree, k)
+				}
+				delete(model, k)
+			}
+		}
+		var expect []int
+		for k := range model {
+			expect = append(expect, k)
+		}
+		slices.Sort(expect)
+		if found := setKeys(s); !slices.Equal(found, expect) || s.Count() != len(expect) {
+			t.Errorf("degree %d: expected keys %v, found %v", degree, expect, found)
+		}
+		if err := s.(*bTreeSet[int]).Validate(); err != nil {
+			t.Errorf("degree %d: %v", degree, err)
+		}
+	}
+}
+
+func TestBTreeSet_Range(t *testing.T) {
+	s := setOf(1, 3, 5, 7, 9, 11, 13, 15)
+	var found []int
+	s.Range(4, 11, func(k int) bool {
+		found = append(found, k)
+		return true
+	})
+	if expect := []int{5, 7, 9, 11}; !slices.Equal(found, expect) {
+		t.Errorf("expected range %v, found %v", expect, found)
+	}
+	found = nil
+	s.Range(0, 100, func(k int) bool {
+		found = append(found, k)
+		return len(found) < 2
+	})
+	if len(found) != 2 {
+		t.Errorf("expected range to stop after %d keys, found %v", 2, found)
+	}
+}
+
+func TestBTreeSet_SetOperations(t *testing.T) {
+	a := setOf(1, 2, 3, 5, 8, 13, 21)
+	b := setOf(2, 3, 4, 8, 16, 32)
+	for _, c := range []struct {
+		name   string
+		set    BTreeSet[int]
+		expect []int
+	}{
+		{"union", a.Union(b), []int{1, 2, 3, 4, 5, 8, 13, 16, 21, 32}},
+		{"intersect", a.Intersect(b), []int{2, 3, 8}},
+		{"difference", a.Difference(b), []int{1, 5, 13, 21}},
+		{"symmetric difference", a.SymmetricDifference(b), []int{1, 4, 5, 13, 16, 21, 32}},
+	} {
+		if found := setKeys(c.set); !slices.Equal(found, c.expect) {
+			t.Errorf("%s: expected keys %v, found %v", c.name, c.expect, found)
+		}
+	}
+}
+
+func TestBTreeSet_EntrySize(t *testing.T) {
+	if size := unsafe.Sizeof(nodeEntry[string, struct{}]{}); size != unsafe.Sizeof("") {
+		t.Errorf("expected set entry of %d bytes, the size of its key, found %d", unsafe.Sizeof(""), size)
+	}
+	if size := unsafe.Sizeof(nodeEntry[int, struct{}]{}); size != unsafe.Sizeof(0) {
+		t.Errorf("expected set entry of %d bytes, the size of its key, found %d", unsafe.Sizeof(0), size)
+	}
+}
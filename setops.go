@@ -0,0 +1,148 @@
+package btree
+
+import (
+	"cmp"
+	"context"
+)
+
+// ConflictFunc resolves the value of a key found in both trees of a set operation.
+// It is given the value from each tree, and returns the value for the resulting tree.
+type ConflictFunc[K cmp.Ordered, V any] func(key K, a, b *V) *V
+
+// Union returns a new tree of the keys in either tree.
+// Values of keys in both trees are resolved by the given function, or taken from a when it is nil.
+func Union[K cmp.Ordered, V any](a, b BTree[K, V], resolve ConflictFunc[K, V]) BTree[K, V] {
+	return mergeTrees(a, b, func(key K, av, bv *V, inA, inB bool) (*V, bool) {
+		if inA && inB {
+			return resolveConflict(resolve, key, av, bv), true
+		}
+		if inA {
+			return av, true
+		}
+		return bv, true
+	})
+}
+
+// Intersect returns a new tree of the keys in both trees.
+// Their values are resolved by the given function, or taken from a when it is nil.
+func Intersect[K cmp.Ordered, V any](a, b BTree[K, V], resolve ConflictFunc[K, V]) BTree[K, V] {
+	return mergeTrees(a, b, func(key K, av, bv *V, inA, inB bool) (*V, bool) {
+		if inA && inB {
+			return resolveConflict(resolve, key, av, bv), true
+		}
+		return nil, false
+	})
+}
+
+// Difference returns a new tree of the keys in a which are not in b.
+func Difference[K cmp.Ordered, V any](a, b BTree[K, V]) BTree[K, V] {
+	return mergeTrees(a, b, func(key K, av, bv *V, inA, inB bool) (*V, bool) {
+		return av, inA && !inB
+	})
+}
+
+// SymmetricDifference returns a new tree of the keys in only one of the trees.
+func SymmetricDifference[K cmp.Ordered, V any](a, b BTree[K, V]) BTree[K, V] {
+	return mergeTrees(a, b, func(key K, av, bv *V, inA, inB bool) (*V, bool) {
+		if inA {
+			return av, !inB
+		}
+		return bv, true
+	})
+}
+
+func resolveConflict[K cmp.Ordered, V any](resolve ConflictFunc[K, V], key K, a, b *V) *V {
+	if resolve == nil {
+		return a
+	}
+	return resolve(key, a, b)
+}
+
+// mergeTrees walks the keys of both trees together, in order, adding to a new tree, of the same degree as a,
+// each key for which fn returns true, with the value it returns.
+func mergeTrees[K cmp.Ordered, V any](a, b BTree[K, V], fn func(key K, av, bv *V, inA, inB bool) (*V, bool)) BTree[K, V] {
+	result := newBTree[K, V](a.Degree())
+	walkTrees(a, b, func(key K, av, bv *V, inA, inB bool) {
+		if v, ok := fn(key, av, bv, inA, inB); ok {
+			_ = result.Add(key, v)
+		}
+	})
+	return result
+}
+
+// walkTrees calls fn with every key in either tree, in order, with its value in each tree and whether it is in each tree.
+func walkTrees[K cmp.Ordered, V any](a, b BTree[K, V], fn func(key K, av, bv *V, inA, inB bool)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ca, cb := newEntryCursor(ctx, a), newEntryCursor(ctx, b)
+	for ca.ok || cb.ok {
+		var key K
+		var av, bv *V
+		c := 0
+		switch {
+		case !cb.ok:
+			c = -1
+		case !ca.ok:
+			c = 1
+		default:
+			c = cmp.Compare(ca.entry.Key, cb.entry.Key)
+		}
+		if c <= 0 {
+			key, av = ca.entry.Key, ca.entry.Value
+		}
+		if c >= 0 {
+			key, bv = cb.entry.Key, cb.entry.Value
+		}
+		fn(key, av, bv, c <= 0, c >= 0)
+		if c <= 0 {
+			ca.next()
+		}
+		if c >= 0 {
+			cb.next()
+		}
+	}
+}
+
+// entryCursor reads the entries of a tree in key order, one at a time.
+// entry is the current entry, while ok is true.
+type entryCursor[K cmp.Ordered, V any] struct {
+	entry nodeEntry[K, *V]
+	ok    bool
+	read  func() (nodeEntry[K, *V], bool)
+}
+
+func (c *entryCursor[K, V]) next() {
+	c.entry, c.ok = c.read()
+}
+
+// newEntryCursor returns a cursor on the first entry of the given tree.
+// The nodes of a tree created by NewBTree are read directly, any other tree is read by its keys.
+func newEntryCursor[K cmp.Ordered, V any](ctx context.Context, tree BTree[K, V]) *entryCursor[K, V] {
+	c := &entryCursor[K, V]{}
+	if bt, ok := tree.(*bTree[K, V]); ok {
+		it := newTreeIterator(bt.rootnode)
+		var entries []nodeEntry[K, *V]
+		c.read = func() (nodeEntry[K, *V], bool) {
+			for len(entries) == 0 {
+				if !it.HasNext() {
+					return nodeEntry[K, *V]{}, false
+				}
+				entries = it.Next()
+			}
+			e := entries[0]
+			entries = entries[1:]
+			return e, true
+		}
+	} else {
+		keys := tree.Keys(ctx)
+		c.read = func() (nodeEntry[K, *V], bool) {
+			k, ok := <-keys
+			if !ok {
+				return nodeEntry[K, *V]{}, false
+			}
+			return nodeEntry[K, *V]{Key: k, Value: tree.Get(k)}, true
+		}
+	}
+	c.next()
+	return c
+}
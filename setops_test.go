@@ -0,0 +1,72 @@
+package btree
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func treeOfKeys(keys ...int) BTree[int, string] {
+	bt := NewBTree[int, string](3)
+	for _, k := range keys {
+		v := strconv.Itoa(k)
+		_ = bt.Add(k, &v)
+	}
+	return bt
+}
+
+func keysOf(bt BTree[int, string]) []int {
+	var keys []int
+	for k := range bt.Keys(context.Background()) {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func TestSetOperations(t *testing.T) {
+	a := treeOfKeys(1, 2, 3, 5, 8, 13, 21)
+	b := NewConcurrentBTree(treeOfKeys(2, 3, 4, 8, 16, 32))
+	for _, c := range []struct {
+		name   string
+		tree   BTree[int, string]
+		expect []int
+	}{
+		{"union", Union(a, b, nil), []int{1, 2, 3, 4, 5, 8, 13, 16, 21, 32}},
+		{"intersect", Intersect(a, b, nil), []int{2, 3, 8}},
+		{"difference", Difference(a, b), []int{1, 5, 13, 21}},
+		{"symmetric difference", SymmetricDifference(a, b), []int{1, 4, 5, 13, 16, 21, 32}},
+		{"empty union", Union(a, NewBTree[int, string](3), nil), keysOf(a)},
+		{"empty intersect", Intersect(NewBTree[int, string](3), b, nil), nil},
+	} {
+		if found := keysOf(c.tree); !slices.Equal(found, c.expect) {
+			t.Errorf("%s: expected keys %v, found %v", c.name, c.expect, found)
+		}
+		if err := validateTree(c.tree); c.expect != nil && err != nil {
+			t.Errorf("%s: %v", c.name, err)
+		}
+	}
+	if a.Count() != 7 || b.Count() != 6 {
+		t.Error("expected trees to be unchanged")
+	}
+}
+
+func TestSetOperations_Conflict(t *testing.T) {
+	a := treeOfKeys(1, 2, 3)
+	b := treeOfKeys(2, 3, 4)
+	resolve := func(key int, av, bv *string) *string {
+		v := *av + "+" + *bv
+		return &v
+	}
+	u := Union(a, b, resolve)
+	if v := u.Get(2); v == nil || *v != "2+2" {
+		t.Errorf("expected resolved value for key %d, found %v", 2, v)
+	}
+	if v := u.Get(1); v == nil || *v != "1" {
+		t.Errorf("expected unresolved value for key %d, found %v", 1, v)
+	}
+	i := Intersect(a, b, resolve)
+	if v := i.Get(3); v == nil || *v != "3+3" {
+		t.Errorf("expected resolved value for key %d, found %v", 3, v)
+	}
+}
@@ -0,0 +1,158 @@
+package btree
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// snapshotMagic identifies a snapshot file.
+const snapshotMagic = "btree-snapshot"
+
+const snapshotVersion = 1
+
+// snapshotHeader precedes the entries in a snapshot.
+// Count is the number of entries following, which allows a truncated snapshot to be detected.
+type snapshotHeader struct {
+	Magic   string
+	Version int
+	Degree  int
+	Count   int
+}
+
+// snapshotEntry is a single key/value pair in a snapshot.
+// As with walRecord, HasValue distinguishes a nil value from a pointer to a zero value.
+type snapshotEntry[K cmp.Ordered, V any] struct {
+	Key      K
+	Value    *V
+	HasValue bool
+}
+
+// WriteSnapshot writes every entry of the given tree, in key order, to the given writer.
+// The tree must not be written to until WriteSnapshot returns.
+func WriteSnapshot[K cmp.Ordered, V any](w io.Writer, tree BTree[K, V]) error {
+	bw := bufio.NewWriter(w)
+	enc := gob.NewEncoder(bw)
+	header := snapshotHeader{
+		Magic:   snapshotMagic,
+		Version: snapshotVersion,
+		Degree:  tree.Degree(),
+		Count:   tree.Count(),
+	}
+	if err := enc.Encode(header); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for k := range tree.Keys(ctx) {
+		v := tree.Get(k)
+		if err := enc.Encode(snapshotEntry[K, V]{Key: k, Value: v, HasValue: v != nil}); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// ReadSnapshot reads a snapshot written by WriteSnapshot, adding all of its entries to the given tree.
+func ReadSnapshot[K cmp.Ordered, V any](r io.Reader, tree BTree[K, V]) error {
+	dec := gob.NewDecoder(bufio.NewReader(r))
+	var header snapshotHeader
+	if err := dec.Decode(&header); err != nil {
+		return fmt.Errorf("failed to read snapshot header  %w", err)
+	}
+	if header.Magic != snapshotMagic {
+		return fmt.Errorf("not a snapshot")
+	}
+	if header.Version != snapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d", header.Version)
+	}
+	for i := 0; i < header.Count; i++ {
+		var e snapshotEntry[K, V]
+		if err := dec.Decode(&e); err != nil {
+			return fmt.Errorf("failed to read snapshot entry %d of %d  %w", i, header.Count, err)
+		}
+		if e.HasValue && e.Value == nil {
+			e.Value = new(V)
+		}
+		if err := tree.Add(e.Key, e.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveSnapshot writes a snapshot of the given tree to the given path.
+// The snapshot is written to a temporary file which replaces path once synced,
+// so path always holds either the previous snapshot or the complete new one.
+func SaveSnapshot[K cmp.Ordered, V any](path string, tree BTree[K, V]) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := WriteSnapshot(f, tree); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return err
+	}
+	syncDir(filepath.Dir(path))
+	return nil
+}
+
+// copyFrom copies the contents of src, from the given offset to its end, into dst.
+func copyFrom(dst io.Writer, src io.ReadSeeker, offset int64) error {
+	if _, err := src.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	_, err := io.Copy(dst, src)
+	return err
+}
+
+// syncDir syncs the given directory, so a file renamed into it survives a crash.
+// Not all platforms support syncing a directory, so any error is ignored.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	d.Close()
+}
+
+// LoadSnapshot reads the snapshot at the given path into the given tree.
+func LoadSnapshot[K cmp.Ordered, V any](path string, tree BTree[K, V]) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return ReadSnapshot(f, tree)
+}
+
+// copyTree returns a copy of the given tree, which shares its values but none of its nodes.
+func copyTree[K cmp.Ordered, V any](tree BTree[K, V]) BTree[K, V] {
+	if bt, ok := tree.(*bTree[K, V]); ok {
+		return bt.clone()
+	}
+	nt := NewBTree[K, V](tree.Degree())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for k := range tree.Keys(ctx) {
+		_ = nt.Add(k, tree.Get(k))
+	}
+	return nt
+}
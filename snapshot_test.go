@@ -0,0 +1,65 @@
+package btree
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+func TestSnapshot_RoundTrip(t *testing.T) {
+	bt := createTestTree(3, 50)
+	empty := ""
+	if err := bt.Add(100, &empty); err != nil {
+		t.Error(err)
+	}
+	if err := bt.Add(101, nil); err != nil {
+		t.Error(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteSnapshot[int, string](&buf, bt); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	nt := NewBTree[int, string](5)
+	if err := ReadSnapshot(bytes.NewReader(data), nt); err != nil {
+		t.Fatal(err)
+	}
+	if nt.Count() != bt.Count() {
+		t.Errorf("expected %d entries read from snapshot, found %d", bt.Count(), nt.Count())
+	}
+	if err := checkContains(nt, 50); err != nil {
+		t.Error(err)
+	}
+	if v := nt.Get(100); v == nil || *v != "" {
+		t.Errorf("expected empty string value for key %d, got %v", 100, v)
+	}
+	if v := nt.Get(101); v != nil {
+		t.Errorf("expected nil value for key %d, got %v", 101, *v)
+	}
+
+	if err := ReadSnapshot(bytes.NewReader(data[:len(data)/2]), NewBTree[int, string](3)); err == nil {
+		t.Error("Expected error reading truncated snapshot, got nil")
+	}
+	if err := ReadSnapshot(bytes.NewReader([]byte("not a snapshot")), NewBTree[int, string](3)); err == nil {
+		t.Error("Expected error reading invalid snapshot, got nil")
+	}
+}
+
+func TestSnapshot_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tree.snap")
+	bt := createTestTree(3, 20)
+	if err := SaveSnapshot[int, string](path, bt); err != nil {
+		t.Fatal(err)
+	}
+	nt := NewBTree[int, string](3)
+	if err := LoadSnapshot(path, nt); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkContains(nt, 20); err != nil {
+		t.Error(err)
+	}
+	if err := validateTree(nt); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,313 @@
+package btree
+
+import (
+	"cmp"
+	"fmt"
+)
+
+// SplitAt moves the keys less than the given key into the left tree, and the rest into the right tree.
+// The split is made by dividing the nodes along the path to the key, rather than adding each key again.
+// The tree is left empty, without sending any events to its watchers.
+// The nodes of the smaller half are counted, for the MemoryUsage of each half.
+func (b *bTree[K, V]) SplitAt(key K) (left, right BTree[K, V], err error) {
+	l, r := b.splitAt(key)
+	return l, r, nil
+}
+
+// splitAt is SplitAt, returning the halves as the trees they are.
+func (b *bTree[K, V]) splitAt(key K) (left, right *bTree[K, V]) {
+	l, _, e, r, rh := b.split(b.rootnode, b.rootnode.height(), key)
+	if e != nil {
+		b.usage.nodes++
+		r, _ = b.join(&node[K, *V]{}, 0, *e, r, rh)
+	}
+	// the counts are of both halves, so only the smaller need be counted to know those of the other
+	lu, ru := divideUsage(b.usage, l, r)
+	b.rootnode = &node[K, *V]{}
+	b.usage.reset()
+	return b.withRoot(l, lu), b.withRoot(r, ru)
+}
+
+// Join combines two trees, of the same degree, into one.
+// Every key in the left tree must be less than every key in the right.
+// The trees are joined by adding the smaller tree to the edge of the larger one, at its height,
+// so only the nodes along that edge are changed. The two trees are left empty.
+// Trees created by NewBTree, or NewConcurrentBTree wrapping them, can be joined.
+func Join[K cmp.Ordered, V any](left, right BTree[K, V]) (BTree[K, V], error) {
+	if left == right {
+		return nil, fmt.Errorf("can not join a tree to itself")
+	}
+	if left.Degree() != right.Degree() {
+		return nil, fmt.Errorf("can not join trees of degree %d and %d", left.Degree(), right.Degree())
+	}
+	switch l := left.(type) {
+	case *bTree[K, V]:
+		r, ok := right.(*bTree[K, V])
+		if !ok {
+			return nil, fmt.Errorf("can not join trees of type %T and %T", left, right)
+		}
+		return l.joinTree(r)
+	case *concurrentTree[K, V]:
+		r, ok := right.(*concurrentTree[K, V])
+		if !ok {
+			return nil, fmt.Errorf("can not join trees of type %T and %T", left, right)
+		}
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		tree, err := Join(l.tree, r.tree)
+		if err != nil {
+			return nil, err
+		}
+		return NewConcurrentBTree(tree), nil
+	default:
+		return nil, fmt.Errorf("can not join trees of type %T", left)
+	}
+}
+
+// joinTree moves the nodes of this tree, and the given tree of greater keys, into a new tree.
+func (b *bTree[K, V]) joinTree(right *bTree[K, V]) (BTree[K, V], error) {
+	if le, re := b.rootnode.lastEntry(), right.rootnode.firstEntry(); le != nil && re != nil && !cmp.Less(le.Key, re.Key) {
+		return nil, fmt.Errorf("can not join trees with overlapping keys, left key %v is not less than right key %v", le.Key, re.Key)
+	}
+	b.usage.add(right.usage)
+	root, _ := b.concat(b.rootnode, b.rootnode.height(), right.rootnode, right.rootnode.height())
+	usage := *b.usage
+	b.rootnode, right.rootnode = &node[K, *V]{}, &node[K, *V]{}
+	b.usage.reset()
+	right.usage.reset()
+	return b.withRoot(root, &usage), nil
+}
+
+// withRoot returns a new tree, of the same degree as this one, with the given root node and its counts.
+func (b *bTree[K, V]) withRoot(root *node[K, *V], usage *memoryUsage[*V]) *bTree[K, V] {
+	return &bTree[K, V]{
+		nodeTree: nodeTree[K, *V]{rootnode: root, degree: b.degree, usage: usage},
+		watches:  &watchList[K, V]{},
+	}
+}
+
+// DeleteRange removes every key from lo to hi, inclusive, returning the number of keys removed.
+// The tree is split either side of the range, detaching whole subtrees lying inside it,
+// and the two remaining trees joined again, so only the nodes along the two boundary paths are rebalanced.
+func (b *bTree[K, V]) DeleteRange(lo, hi K) int {
+	if cmp.Less(hi, lo) || b.rootnode.IsEmpty() {
+		return 0
+	}
+	left, lh, first, rest, rh := b.split(b.rootnode, b.rootnode.height(), lo)
+	mid, _, last, right, rh := b.split(rest, rh, hi)
+
+	// the entries either side of the range were counted out by split, those between go with the nodes of mid
+	gone := &memoryUsage[*V]{sizer: b.usage.sizer}
+	mid.countUsage(gone)
+	b.usage.sub(gone)
+
+	var removed []nodeEntry[K, *V]
+	if first != nil {
+		removed = append(removed, *first)
+	}
+	mid.ascend(nil, false, func(e *nodeEntry[K, *V]) bool {
+		removed = append(removed, *e)
+		return true
+	})
+	if last != nil {
+		removed = append(removed, *last)
+	}
+
+	b.rootnode, _ = b.concat(left, lh, right, rh)
+	for _, e := range removed {
+		b.notifyRemove(e.Key, e.Value)
+	}
+	return len(removed)
+}
+
+// height returns the number of levels below this node.
+func (n *node[K, S]) height() int {
+	h := 0
+	for !n.IsLeaf() {
+		h++
+		n = &n.Children[0]
+	}
+	return h
+}
+
+// firstEntry returns the entry with the smallest key in this node and its children.
+func (n *node[K, S]) firstEntry() *nodeEntry[K, S] {
+	for !n.IsLeaf() {
+		n = &n.Children[0]
+	}
+	if len(n.Entries) == 0 {
+		return nil
+	}
+	return &n.Entries[0]
+}
+
+// lastEntry returns the entry with the greatest key in this node and its children.
+func (n *node[K, S]) lastEntry() *nodeEntry[K, S] {
+	for !n.IsLeaf() {
+		n = n.LastChild()
+	}
+	return n.LastEntry()
+}
+
+// split divides the subtree, of the given height, into a subtree of the keys less than the given key,
+// the entry of the key, if it exists, and a subtree of the keys greater than the key.
+// The subtree is consumed, its nodes reused by those returned, along with their heights.
+// The usage is kept of the nodes created and dropped, and of the entries taken out, including the one returned.
+func (b *bTree[K, V]) split(nd *node[K, *V], h int, key K) (*node[K, *V], int, *nodeEntry[K, *V], *node[K, *V], int) {
+	i, e := nd.keyIndex(key)
+	if i < 0 {
+		i = len(nd.Entries)
+	}
+	if e != nil {
+		e = &nodeEntry[K, *V]{Key: e.Key, Value: e.Value}
+		b.usage.removed(e.Value)
+	}
+	if nd.IsLeaf() {
+		left := &node[K, *V]{Entries: append([]nodeEntry[K, *V]{}, nd.Entries[:i]...)}
+		j := i
+		if e != nil {
+			j++
+		}
+		right := &node[K, *V]{Entries: append([]nodeEntry[K, *V]{}, nd.Entries[j:]...)}
+		// the leaf is replaced by its two halves
+		b.usage.nodes++
+		return left, 0, e, right, 0
+	}
+	// this node is replaced by the nodes made of its parts
+	b.usage.nodes--
+	if e != nil {
+		// key divides this node, the children either side become the edges of each half
+		left, lh := b.subNode(nd, 0, i, h)
+		right, rh := b.subNode(nd, i+1, len(nd.Entries), h)
+		return left, lh, e, right, rh
+	}
+	// split the child the key belongs in, and join the parts of this node either side back onto its halves.
+	cl, clh, ce, cr, crh := b.split(&nd.Children[i], h-1, key)
+	left, lh := cl, clh
+	if i > 0 {
+		ln, lnh := b.subNode(nd, 0, i-1, h)
+		b.usage.removed(nd.Entries[i-1].Value)
+		left, lh = b.join(ln, lnh, nd.Entries[i-1], cl, clh)
+	}
+	right, rh := cr, crh
+	if i < len(nd.Entries) {
+		rn, rnh := b.subNode(nd, i+1, len(nd.Entries), h)
+		b.usage.removed(nd.Entries[i].Value)
+		right, rh = b.join(cr, crh, nd.Entries[i], rn, rnh)
+	}
+	return left, lh, ce, right, rh
+}
+
+// subNode returns a new node of the entries of nd, from index 'from' up to 'to', and the children either side of them.
+// When there are no entries, the single child is returned, a level lower.
+func (b *bTree[K, V]) subNode(nd *node[K, *V], from, to, h int) (*node[K, *V], int) {
+	if from == to {
+		return &nd.Children[from], h - 1
+	}
+	b.usage.nodes++
+	return &node[K, *V]{
+		Entries:  append([]nodeEntry[K, *V]{}, nd.Entries[from:to]...),
+		Children: append([]node[K, *V]{}, nd.Children[from:to+1]...),
+	}, h
+}
+
+// concat joins two subtrees, where every key in the left is less than every key in the right.
+// The smallest entry of the right is split from it, to join the two.
+func (b *bTree[K, V]) concat(left *node[K, *V], lh int, right *node[K, *V], rh int) (*node[K, *V], int) {
+	fe := right.firstEntry()
+	// the empty right, or the empty left of its split, is dropped
+	b.usage.nodes--
+	if fe == nil {
+		return left, lh
+	}
+	_, _, sep, right, rh := b.split(right, rh, fe.Key)
+	return b.join(left, lh, *sep, right, rh)
+}
+
+// join combines two subtrees, of the given heights, and the entry between them, into a single subtree.
+// Every key in the left must be less than the entry key, and every key in the right greater than it.
+// Only the nodes down the edge of the taller subtree, to the height of the shorter one, are changed.
+// As with insert, the usage is kept of the entry added and the nodes created, including a new root.
+func (b *bTree[K, V]) join(left *node[K, *V], lh int, sep nodeEntry[K, *V], right *node[K, *V], rh int) (*node[K, *V], int) {
+	if left.IsEmpty() || right.IsEmpty() {
+		nd, h := left, lh
+		if left.IsEmpty() {
+			nd, h = right, rh
+		}
+		// the empty node is dropped
+		b.usage.nodes--
+		if nn := b.add(sep.Key, sep.Value, nd); nn != nil {
+			b.usage.nodes++
+			return nn, h + 1
+		}
+		return nd, h
+	}
+	b.usage.written(sep.Value, sep.Value, false)
+	var nn *node[K, *V]
+	switch {
+	case lh == rh:
+		// the two nodes are replaced by one of them both
+		b.usage.nodes--
+		nd := &node[K, *V]{
+			Entries:  append(append(append([]nodeEntry[K, *V]{}, left.Entries...), sep), right.Entries...),
+			Children: append(append([]node[K, *V]{}, left.Children...), right.Children...),
+		}
+		if len(nd.Entries) < b.degree {
+			return nd, lh
+		}
+		b.usage.nodes += 2
+		return nd.Split(), lh + 1
+	case lh > rh:
+		if nn = b.joinRight(left, lh, sep, right, rh); nn == nil {
+			return left, lh
+		}
+		b.usage.nodes++
+		return nn, lh + 1
+	default:
+		if nn = b.joinLeft(left, lh, sep, right, rh); nn == nil {
+			return right, rh
+		}
+		b.usage.nodes++
+		return nn, rh + 1
+	}
+}
+
+// joinRight adds the entry and right subtree to the end of the node, at the height of the right, down the right edge of nd.
+// As with add, a node is returned when nd is split.
+func (b *bTree[K, V]) joinRight(nd *node[K, *V], h int, sep nodeEntry[K, *V], right *node[K, *V], rh int) *node[K, *V] {
+	nd.agg = nil
+	if h == rh+1 {
+		nd.Entries = append(nd.Entries, sep)
+		nd.Children = append(nd.Children, *right)
+	} else if nn := b.joinRight(nd.LastChild(), h-1, sep, right, rh); nn != nil {
+		nd.Entries = append(nd.Entries, nn.Entries[0])
+		nd.Children[len(nd.Children)-1] = nn.Children[0]
+		nd.Children = append(nd.Children, nn.Children[1])
+	}
+	if len(nd.Entries) < b.degree {
+		return nil
+	}
+	b.usage.nodes++
+	return nd.Split()
+}
+
+// joinLeft adds the left subtree and entry to the start of the node, at the height of the left, down the left edge of nd.
+// As with add, a node is returned when nd is split.
+func (b *bTree[K, V]) joinLeft(left *node[K, *V], lh int, sep nodeEntry[K, *V], nd *node[K, *V], h int) *node[K, *V] {
+	nd.agg = nil
+	if h == lh+1 {
+		nd.Entries = InsertAtIndex(sep, nd.Entries, 0)
+		nd.Children = InsertAtIndex(*left, nd.Children, 0)
+	} else if nn := b.joinLeft(left, lh, sep, &nd.Children[0], h-1); nn != nil {
+		nd.Entries = InsertAtIndex(nn.Entries[0], nd.Entries, 0)
+		nd.Children[0] = nn.Children[1]
+		nd.Children = InsertAtIndex(nn.Children[0], nd.Children, 0)
+	}
+	if len(nd.Entries) < b.degree {
+		return nil
+	}
+	b.usage.nodes++
+	return nd.Split()
+}
@@ -0,0 +1,313 @@
+package btree
+
+import (
+	"math/rand"
+	"os"
+	"strconv"
+	"testing"
+)
+
+func TestBTree_DeleteRange(t *testing.T) {
+	bt := createTestTree(3, 100)
+	if removed := bt.DeleteRange(20, 59); removed != 40 {
+		t.Errorf("expected %d keys removed, removed %d", 40, removed)
+	}
+	if bt.Count() != 60 {
+		t.Errorf("expected %d keys remaining, found %d", 60, bt.Count())
+	}
+	if bt.Get(19) == nil || bt.Get(20) != nil || bt.Get(59) != nil || bt.Get(60) == nil {
+		t.Error("unexpected keys either side of the range")
+	}
+	if err := validateTree(bt); err != nil {
+		t.Error(err)
+	}
+	if removed := bt.DeleteRange(30, 50); removed != 0 {
+		t.Errorf("expected no keys removed from an empty range, removed %d", removed)
+	}
+	if removed := bt.DeleteRange(50, 30); removed != 0 {
+		t.Errorf("expected no keys removed from an inverted range, removed %d", removed)
+	}
+	if removed := bt.DeleteRange(-1, 1000); removed != 60 || !bt.IsEmpty() {
+		t.Errorf("expected all keys removed, removed %d", removed)
+	}
+	_ = bt.Add(1, nil)
+	if bt.Count() != 1 {
+		t.Error("expected emptied tree to be usable")
+	}
+}
+
+func TestBTree_DeleteRange_Random(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, degree := range []int{3, 4, 5, 10} {
+		for n := 0; n < 200; n++ {
+			bt := NewBTree[int, string](degree)
+			model := map[int]bool{}
+			for i := rnd.Intn(500); i > 0; i-- {
+				k := rnd.Intn(1000)
+				v := strconv.Itoa(k)
+				_ = bt.Add(k, &v)
+				model[k] = true
+			}
+			lo := rnd.Intn(1000)
+			hi := lo + rnd.Intn(1000-lo)
+			expect := 0
+			for k := range model {
+				if k >= lo && k <= hi {
+					delete(model, k)
+					expect++
+				}
+			}
+			if removed := bt.DeleteRange(lo, hi); removed != expect {
+				t.Fatalf("degree %d: expected %d keys removed from %d to %d, removed %d", degree, expect, lo, hi, removed)
+			}
+			if err := compareToModel(bt, model); err != nil {
+				t.Fatalf("degree %d: %v", degree, err)
+			}
+			if err := bt.Validate(); err != nil {
+				t.Fatalf("degree %d: %v", degree, err)
+			}
+			// the tree remains usable
+			for k := range model {
+				if rnd.Intn(2) == 0 {
+					if err := bt.Remove(k); err != nil {
+						t.Fatalf("degree %d: %v", degree, err)
+					}
+					delete(model, k)
+				}
+			}
+			if err := compareToModel(bt, model); err != nil {
+				t.Fatalf("degree %d: %v", degree, err)
+			}
+		}
+	}
+}
+
+func TestWAL_DeleteRange(t *testing.T) {
+	path := t.TempDir() + "/test.wal"
+	wt, err := OpenWAL(path, NewBTree[int, string](3), WALOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 50; i++ {
+		v := strconv.Itoa(i)
+		_ = wt.Add(i, &v)
+	}
+	wt.DeleteRange(10, 39)
+	_ = wt.Close()
+
+	wt, err = OpenWAL(path, NewBTree[int, string](3), WALOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.Close()
+	if wt.Count() != 20 || wt.Get(9) == nil || wt.Get(10) != nil || wt.Get(40) == nil {
+		t.Errorf("expected range removed on replay, found %d keys", wt.Count())
+	}
+}
+
+func TestWAL_DeleteRange_LogFailure(t *testing.T) {
+	path := t.TempDir() + "/test.wal"
+	wt, err := OpenWAL(path, NewBTree[int, string](3), WALOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 50; i++ {
+		v := strconv.Itoa(i)
+		_ = wt.Add(i, &v)
+	}
+	w := wt.(*walTree[int, string])
+	file := w.file
+	// a read only handle fails the write of the range
+	if w.file, err = os.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	if removed := wt.DeleteRange(10, 39); removed != 0 || wt.Count() != 50 {
+		t.Errorf("expected nothing removed when the range is not logged, removed %d", removed)
+	}
+	w.file.Close()
+	w.file = file
+	if err := wt.Sync(); err == nil {
+		t.Error("expected sync to report the failure to log the range")
+	}
+	if err := wt.Close(); err == nil {
+		t.Error("expected close to report the failure to log the range")
+	}
+}
+
+func TestBTree_SplitAt(t *testing.T) {
+	bt := createTestTree(3, 100)
+	left, right, err := bt.SplitAt(40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if left.Count() != 40 || right.Count() != 60 {
+		t.Errorf("expected %d and %d keys, found %d and %d", 40, 60, left.Count(), right.Count())
+	}
+	if left.Get(39) == nil || left.Get(40) != nil || right.Get(40) == nil || right.Get(39) != nil {
+		t.Error("unexpected keys either side of the split")
+	}
+	if !bt.IsEmpty() {
+		t.Error("expected split tree to be empty")
+	}
+	for _, tree := range []BTree[int, string]{left, right} {
+		if err := validateTree(tree); err != nil {
+			t.Error(err)
+		}
+	}
+	left, right, _ = createTestTree(3, 10).SplitAt(100)
+	if left.Count() != 10 || !right.IsEmpty() {
+		t.Errorf("expected all keys left of a split above them, found %d and %d", left.Count(), right.Count())
+	}
+}
+
+func TestBTree_Join(t *testing.T) {
+	left, right, _ := createTestTree(3, 100).SplitAt(70)
+	joined, err := Join(left, right)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if joined.Count() != 100 {
+		t.Errorf("expected %d keys, found %d", 100, joined.Count())
+	}
+	if err := validateTree(joined); err != nil {
+		t.Error(err)
+	}
+	if !left.IsEmpty() || !right.IsEmpty() {
+		t.Error("expected joined trees to be empty")
+	}
+	if _, err := Join(createTestTree(3, 10), createTestTree(3, 10)); err == nil {
+		t.Error("expected error joining overlapping trees")
+	}
+	if _, err := Join(NewBTree[int, string](3), NewBTree[int, string](4)); err == nil {
+		t.Error("expected error joining trees of different degree")
+	}
+}
+
+func TestBTree_SplitJoin_Random(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, degree := range []int{3, 4, 5, 10} {
+		for n := 0; n < 200; n++ {
+			bt := NewBTree[int, string](degree)
+			model := map[int]bool{}
+			for i := rnd.Intn(500); i > 0; i-- {
+				k := rnd.Intn(1000)
+				v := strconv.Itoa(k)
+				_ = bt.Add(k, &v)
+				model[k] = true
+			}
+			at := rnd.Intn(1000)
+			left, right, err := bt.SplitAt(at)
+			if err != nil {
+				t.Fatal(err)
+			}
+			leftModel, rightModel := map[int]bool{}, map[int]bool{}
+			for k := range model {
+				if k < at {
+					leftModel[k] = true
+				} else {
+					rightModel[k] = true
+				}
+			}
+			for _, c := range []struct {
+				tree  BTree[int, string]
+				model map[int]bool
+			}{{left, leftModel}, {right, rightModel}} {
+				if err := compareToModel(c.tree, c.model); err != nil {
+					t.Fatalf("degree %d: split at %d: %v", degree, at, err)
+				}
+				if err := c.tree.Validate(); err != nil {
+					t.Fatalf("degree %d: split at %d: %v", degree, at, err)
+				}
+			}
+			joined, err := Join(left, right)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := compareToModel(joined, model); err != nil {
+				t.Fatalf("degree %d: joined at %d: %v", degree, at, err)
+			}
+			if err := joined.Validate(); err != nil {
+				t.Fatalf("degree %d: joined at %d: %v", degree, at, err)
+			}
+		}
+	}
+}
+
+func TestConcurrentBTree_SplitJoin(t *testing.T) {
+	ct := NewConcurrentBTree[int, string](createTestTree(3, 50))
+	left, right, err := ct.SplitAt(25)
+	if err != nil {
+		t.Fatal(err)
+	}
+	joined, err := Join(left, right)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := joined.(*concurrentTree[int, string]); !ok || joined.Count() != 50 {
+		t.Errorf("expected concurrent tree of %d keys, found %T of %d keys", 50, joined, joined.Count())
+	}
+}
+
+func TestWAL_SplitAt(t *testing.T) {
+	path := t.TempDir() + "/test.wal"
+	wt, err := OpenWAL(path, NewBTree[int, string](3), WALOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		v := strconv.Itoa(i)
+		_ = wt.Add(i, &v)
+	}
+	left, right, err := wt.SplitAt(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = wt.Close()
+	if left.Count() != 5 || right.Count() != 15 {
+		t.Errorf("expected %d and %d keys, found %d and %d", 5, 15, left.Count(), right.Count())
+	}
+
+	wt, err = OpenWAL(path, NewBTree[int, string](3), WALOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.Close()
+	if !wt.IsEmpty() {
+		t.Errorf("expected split tree empty on replay, found %d keys", wt.Count())
+	}
+}
+
+func TestWAL_SplitAt_LogFailure(t *testing.T) {
+	path := t.TempDir() + "/test.wal"
+	wt, err := OpenWAL(path, NewBTree[int, string](3), WALOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		v := strconv.Itoa(i)
+		_ = wt.Add(i, &v)
+	}
+	w := wt.(*walTree[int, string])
+	file := w.file
+	// a read only handle fails the write of the split
+	if w.file, err = os.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	if left, right, err := wt.SplitAt(5); err == nil || left != nil || right != nil {
+		t.Error("expected error, and no halves, when the split is not logged")
+	}
+	if left, right, err := NewConcurrentBTree(wt).SplitAt(5); err == nil || left != nil || right != nil {
+		t.Error("expected error, and no halves, from a concurrent tree when the split is not logged")
+	}
+	if wt.Count() != 20 {
+		t.Errorf("expected tree unchanged when the split is not logged, found %d keys", wt.Count())
+	}
+	w.file.Close()
+	w.file = file
+	if err := wt.Sync(); err == nil {
+		t.Error("expected sync to report the failure to log the split")
+	}
+	if err := wt.Close(); err == nil {
+		t.Error("expected close to report the failure to log the split")
+	}
+}
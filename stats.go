@@ -0,0 +1,82 @@
+package btree
+
+import (
+	"cmp"
+)
+
+// TreeStats describes the shape of a tree, and how full its nodes are.
+type TreeStats struct {
+	Nodes  int
+	Leaves int
+	// Height is the number of levels of nodes, one for a tree of only a root.
+	Height int
+	// LevelNodes is the number of nodes at each level, from the root down.
+	LevelNodes []int
+	// MinFill, MaxFill and AvgFill are the fill factors of the nodes, the number of entries of a node,
+	// over the most a node can hold, degree - 1.
+	MinFill, MaxFill, AvgFill float64
+	// EntriesHistogram is the number of nodes holding each number of entries, indexed by the number of entries.
+	EntriesHistogram []int
+}
+
+// add counts a node, at the given level, holding the given number of entries.
+func (s *TreeStats) add(level, entries int, leaf bool, degree int) {
+	s.Nodes++
+	if leaf {
+		s.Leaves++
+	}
+	if level >= len(s.LevelNodes) {
+		s.LevelNodes = append(s.LevelNodes, 0)
+		s.Height = len(s.LevelNodes)
+	}
+	s.LevelNodes[level]++
+	for entries >= len(s.EntriesHistogram) {
+		s.EntriesHistogram = append(s.EntriesHistogram, 0)
+	}
+	s.EntriesHistogram[entries]++
+
+	fill := float64(entries) / float64(degree-1)
+	if s.Nodes == 1 || fill < s.MinFill {
+		s.MinFill = fill
+	}
+	if fill > s.MaxFill {
+		s.MaxFill = fill
+	}
+	// AvgFill holds the total until every node is counted
+	s.AvgFill += fill
+}
+
+// done completes the stats once every node has been counted.
+func (s *TreeStats) done() TreeStats {
+	s.AvgFill /= float64(s.Nodes)
+	return *s
+}
+
+// Stats walks every node of the tree, returning the shape of the tree and how full its nodes are.
+func (b *bTree[K, V]) Stats() TreeStats {
+	var s TreeStats
+	b.rootnode.stats(0, b.degree, &s)
+	return s.done()
+}
+
+func (n *node[K, S]) stats(level, degree int, s *TreeStats) {
+	s.add(level, len(n.Entries), n.IsLeaf(), degree)
+	for i := range n.Children {
+		n.Children[i].stats(level+1, degree, s)
+	}
+}
+
+// Stats walks every node of the tree, returning the shape of the tree and how full its nodes are.
+// The entries of the parent nodes are their separator keys.
+func (b *bPlusTree[K, V]) Stats() TreeStats {
+	var s TreeStats
+	bpStats(b.root, 0, b.degree, &s)
+	return s.done()
+}
+
+func bpStats[K cmp.Ordered, V any](nd *bpNode[K, V], level, degree int, s *TreeStats) {
+	s.add(level, len(nd.keys), nd.isLeaf(), degree)
+	for _, c := range nd.children {
+		bpStats(c, level+1, degree, s)
+	}
+}
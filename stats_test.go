@@ -0,0 +1,49 @@
+package btree
+
+import (
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	b := newBTree[int, string](3)
+	s := b.Stats()
+	if s.Nodes != 1 || s.Leaves != 1 || s.Height != 1 || s.AvgFill != 0 || len(s.EntriesHistogram) != 1 {
+		t.Errorf("unexpected stats of an empty tree %+v", s)
+	}
+	// a root of one entry, over two leaves of one and two entries
+	for i := 1; i <= 4; i++ {
+		_ = b.Add(i, new(string))
+	}
+	s = b.Stats()
+	if s.Nodes != 3 || s.Leaves != 2 || s.Height != 2 || len(s.LevelNodes) != 2 || s.LevelNodes[0] != 1 || s.LevelNodes[1] != 2 {
+		t.Errorf("unexpected shape %+v", s)
+	}
+	if s.MinFill != 0.5 || s.MaxFill != 1 || s.AvgFill != 4.0/6 {
+		t.Errorf("unexpected fill factors %v, %v, %v", s.MinFill, s.MaxFill, s.AvgFill)
+	}
+	if len(s.EntriesHistogram) != 3 || s.EntriesHistogram[1] != 2 || s.EntriesHistogram[2] != 1 {
+		t.Errorf("unexpected histogram %v", s.EntriesHistogram)
+	}
+}
+
+func TestStats_Counts(t *testing.T) {
+	for _, tree := range []BTree[int, string]{NewBTree[int, string](5), NewBPlusTree[int, string](5)} {
+		fillTree(tree, 1000)
+		tree.DeleteRange(200, 600)
+		s := tree.Stats()
+		nodes, levels, histogram := 0, 0, 0
+		for _, n := range s.LevelNodes {
+			nodes += n
+			levels++
+		}
+		for _, n := range s.EntriesHistogram {
+			histogram += n
+		}
+		if nodes != s.Nodes || histogram != s.Nodes || levels != s.Height || s.Height != tree.Depth()+1 {
+			t.Errorf("expected levels and histogram to count %d nodes in %d levels, found %+v", s.Nodes, tree.Depth()+1, s)
+		}
+		if s.LevelNodes[s.Height-1] != s.Leaves || s.MinFill <= 0 || s.MaxFill > 1 || s.AvgFill < s.MinFill || s.AvgFill > s.MaxFill {
+			t.Errorf("unexpected stats %+v", s)
+		}
+	}
+}
@@ -5,9 +5,9 @@ import (
 )
 
 type treeIterator[K cmp.Ordered, V any] struct {
-	next      []nodeEntry[K, V]
-	nodes     stackSlice[*node[K, V]]
-	linkEntry *nodeEntry[K, V]
+	next      []nodeEntry[K, *V]
+	nodes     stackSlice[*node[K, *V]]
+	linkEntry *nodeEntry[K, *V]
 }
 
 func (it *treeIterator[K, V]) HasNext() bool {
@@ -17,7 +17,7 @@ func (it *treeIterator[K, V]) HasNext() bool {
 	return it.next != nil
 }
 
-func (it *treeIterator[K, V]) Next() []nodeEntry[K, V] {
+func (it *treeIterator[K, V]) Next() []nodeEntry[K, *V] {
 	if !it.HasNext() {
 		return nil
 	}
@@ -34,9 +34,9 @@ func (it treeIterator[K, V]) Depth() int {
 	return d
 }
 
-func (it *treeIterator[K, V]) getNext() []nodeEntry[K, V] {
+func (it *treeIterator[K, V]) getNext() []nodeEntry[K, *V] {
 	if it.linkEntry != nil {
-		le := []nodeEntry[K, V]{*it.linkEntry}
+		le := []nodeEntry[K, *V]{*it.linkEntry}
 		it.linkEntry = nil
 		return le
 	}
@@ -60,7 +60,7 @@ func (it *treeIterator[K, V]) getNext() []nodeEntry[K, V] {
 // and returns the seperator entry dividing the two sibling nodes.
 // If the current leaf is the last sibling in the parent, recursively calls itself with the parent node
 // If no more nodes are available, nil is returned.
-func (it *treeIterator[K, V]) skipToNextNode(child *node[K, V]) *nodeEntry[K, V] {
+func (it *treeIterator[K, V]) skipToNextNode(child *node[K, *V]) *nodeEntry[K, *V] {
 	parent, ok := it.nodes.Peek()
 	if !ok {
 		return nil
@@ -79,7 +79,7 @@ func (it *treeIterator[K, V]) skipToNextNode(child *node[K, V]) *nodeEntry[K, V]
 	return &parent.Entries[nextindex-1]
 }
 
-func (it *treeIterator[K, V]) skipToFirstLeaf(n *node[K, V]) *node[K, V] {
+func (it *treeIterator[K, V]) skipToFirstLeaf(n *node[K, *V]) *node[K, *V] {
 	for {
 		if n.IsLeaf() {
 			break
@@ -90,7 +90,7 @@ func (it *treeIterator[K, V]) skipToFirstLeaf(n *node[K, V]) *node[K, V] {
 	return n
 }
 
-func indexOfChild[K cmp.Ordered, V any](parent, child *node[K, V]) int {
+func indexOfChild[K cmp.Ordered, V any](parent, child *node[K, *V]) int {
 	for i := range parent.Children {
 		if &parent.Children[i] == child {
 			return i
@@ -99,9 +99,9 @@ func indexOfChild[K cmp.Ordered, V any](parent, child *node[K, V]) int {
 	return -1
 }
 
-func newTreeIterator[K cmp.Ordered, V any](rootNode *node[K, V]) *treeIterator[K, V] {
+func newTreeIterator[K cmp.Ordered, V any](rootNode *node[K, *V]) *treeIterator[K, V] {
 	it := &treeIterator[K, V]{
-		nodes: stackSlice[*node[K, V]]{},
+		nodes: stackSlice[*node[K, *V]]{},
 	}
 	if rootNode != nil && len(rootNode.Entries) > 0 {
 		it.nodes.Push(rootNode)
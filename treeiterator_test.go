@@ -88,7 +88,7 @@ func TestTreeIterator_Next(t *testing.T) {
 	testCount = 15
 	bt = createTestTree(degree, testCount)
 	walker = newTreeIterator(bt.rootnode)
-	var total []nodeEntry[int, string]
+	var total []nodeEntry[int, *string]
 	for walker.HasNext() {
 		next = walker.Next()
 		if len(next) < 1 {
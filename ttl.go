@@ -0,0 +1,308 @@
+package btree
+
+import (
+	"cmp"
+	"context"
+	"sync"
+	"time"
+)
+
+// Clock gives the current time to a TTLTree.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// ManualClock is a Clock which only moves when told to, for testing expiry.
+// A ManualClock is safe for concurrent use.
+type ManualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by the given duration.
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// NewManualClock creates a ManualClock set to the given time.
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now}
+}
+
+// TTLOptions configures a TTLTree.
+type TTLOptions[K cmp.Ordered, V any] struct {
+	// Clock gives the current time. When nil, the system clock is used.
+	Clock Clock
+	// ReapInterval is the time between background reaps of expired keys. Zero disables the background reaper.
+	ReapInterval time.Duration
+	// OnExpire, when set, is called with each key, and its value, removed because it expired.
+	// It is called after the tree is unlocked, so it may use the tree.
+	OnExpire func(key K, value *V)
+}
+
+// TTLTree is a tree in which keys may be given a time to live, after which they expire and are removed.
+// Expired keys are removed when next read, and in bulk by Reap, which can be run in the background.
+// A second tree, of expiry times, is kept alongside the keys, so a reap splits off every expired time at once
+// and only reads the keys which have expired.
+// A TTLTree is safe for concurrent use.
+type TTLTree[K cmp.Ordered, V any] struct {
+	tree *bTree[K, ttlEntry[V]]
+	// expiries holds the keys expiring at each time, in unix nanoseconds.
+	expiries *bTree[int64, []K]
+	clock    Clock
+	onExpire func(key K, value *V)
+	mu       sync.RWMutex
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// ttlEntry is the value of a key, and the time it expires, in unix nanoseconds, when it has a time to live.
+type ttlEntry[V any] struct {
+	value   *V
+	expires int64
+	hasTTL  bool
+}
+
+func (e *ttlEntry[V]) expired(now int64) bool {
+	return e.hasTTL && e.expires <= now
+}
+
+func (t *TTLTree[K, V]) Degree() int {
+	return t.tree.Degree()
+}
+
+// Count returns the number of keys in the tree, including any expired keys not yet removed.
+func (t *TTLTree[K, V]) Count() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.tree.Count()
+}
+
+// Keys returns the keys which have not expired when it is called.
+// The keys are collected before returning, so the tree may be written to while the keys are read.
+func (t *TTLTree[K, V]) Keys(ctx context.Context) <-chan K {
+	now := t.clock.Now().UnixNano()
+	var keys []K
+	t.mu.RLock()
+	t.tree.rootnode.ascend(nil, false, func(e *nodeEntry[K, *ttlEntry[V]]) bool {
+		if !e.Value.expired(now) {
+			keys = append(keys, e.Key)
+		}
+		return true
+	})
+	t.mu.RUnlock()
+
+	ch := make(chan K)
+	go func(ch chan<- K) {
+		defer close(ch)
+		for _, k := range keys {
+			select {
+			case <-ctx.Done():
+				return
+			case ch <- k:
+			}
+		}
+	}(ch)
+	return ch
+}
+
+// Get returns the value of the given key, or nil if it is not in the tree or has expired.
+// An expired key is removed, and OnExpire called, before returning.
+func (t *TTLTree[K, V]) Get(key K) *V {
+	now := t.clock.Now().UnixNano()
+	t.mu.RLock()
+	e := t.tree.Get(key)
+	t.mu.RUnlock()
+	if e == nil {
+		return nil
+	}
+	if !e.expired(now) {
+		return e.value
+	}
+
+	t.mu.Lock()
+	// the key may have been replaced, or removed, since it was read
+	if e = t.tree.Get(key); e == nil || !e.expired(now) {
+		t.mu.Unlock()
+		if e == nil {
+			return nil
+		}
+		return e.value
+	}
+	t.remove(key, e)
+	t.mu.Unlock()
+	if t.onExpire != nil {
+		t.onExpire(key, e.value)
+	}
+	return nil
+}
+
+// TTL returns the time left before the given key expires.
+// Returns false if the key is not in the tree, has expired, or has no time to live.
+func (t *TTLTree[K, V]) TTL(key K) (time.Duration, bool) {
+	now := t.clock.Now().UnixNano()
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	e := t.tree.Get(key)
+	if e == nil || !e.hasTTL || e.expired(now) {
+		return 0, false
+	}
+	return time.Duration(e.expires - now), true
+}
+
+// Add sets the given key to the given value, with no time to live, replacing any time to live it had.
+func (t *TTLTree[K, V]) Add(key K, value *V) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.add(key, &ttlEntry[V]{value: value})
+}
+
+// AddWithTTL sets the given key to the given value, expiring once the given time to live has passed.
+func (t *TTLTree[K, V]) AddWithTTL(key K, value *V, ttl time.Duration) error {
+	expires := t.clock.Now().Add(ttl).UnixNano()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.add(key, &ttlEntry[V]{value: value, expires: expires, hasTTL: true}); err != nil {
+		return err
+	}
+	var keys []K
+	if old := t.expiries.Get(expires); old != nil {
+		keys = append(keys, *old...)
+	}
+	keys = append(keys, key)
+	return t.expiries.Add(expires, &keys)
+}
+
+// Remove removes the given key, returning an error if it is not in the tree.
+func (t *TTLTree[K, V]) Remove(key K) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if e := t.tree.Get(key); e != nil {
+		t.unexpire(key, e)
+	}
+	return t.tree.Remove(key)
+}
+
+// Reap removes every expired key, calling OnExpire with each, and returns the number of keys removed.
+func (t *TTLTree[K, V]) Reap() int {
+	now := t.clock.Now().UnixNano()
+	t.mu.Lock()
+	expired, rest := t.expiries.splitAt(now + 1)
+	t.expiries = rest
+
+	var removed []nodeEntry[K, *V]
+	expired.rootnode.ascend(nil, false, func(e *nodeEntry[int64, *[]K]) bool {
+		for _, key := range *e.Value {
+			if te := t.tree.Get(key); te != nil && te.expired(now) {
+				_ = t.tree.Remove(key)
+				removed = append(removed, nodeEntry[K, *V]{Key: key, Value: te.value})
+			}
+		}
+		return true
+	})
+	t.mu.Unlock()
+
+	if t.onExpire != nil {
+		for _, e := range removed {
+			t.onExpire(e.Key, e.Value)
+		}
+	}
+	return len(removed)
+}
+
+// Close stops the background reaper, if any.
+func (t *TTLTree[K, V]) Close() {
+	if t.done != nil {
+		close(t.done)
+		t.wg.Wait()
+		t.done = nil
+	}
+}
+
+// add sets the entry of the given key, removing the expiry of any entry it replaces.
+// Caller must hold the write lock.
+func (t *TTLTree[K, V]) add(key K, e *ttlEntry[V]) error {
+	if old := t.tree.Get(key); old != nil {
+		t.unexpire(key, old)
+	}
+	return t.tree.Add(key, e)
+}
+
+// remove removes the given key, and its expiry.
+// Caller must hold the write lock.
+func (t *TTLTree[K, V]) remove(key K, e *ttlEntry[V]) {
+	t.unexpire(key, e)
+	_ = t.tree.Remove(key)
+}
+
+// unexpire removes the given key from the keys expiring at the time of the given entry.
+// Caller must hold the write lock.
+func (t *TTLTree[K, V]) unexpire(key K, e *ttlEntry[V]) {
+	if !e.hasTTL {
+		return
+	}
+	old := t.expiries.Get(e.expires)
+	if old == nil {
+		return
+	}
+	for i, k := range *old {
+		if k != key {
+			continue
+		}
+		if len(*old) == 1 {
+			_ = t.expiries.Remove(e.expires)
+			return
+		}
+		keys := RemoveAtIndex(*old, i)
+		_ = t.expiries.Add(e.expires, &keys)
+		return
+	}
+}
+
+func (t *TTLTree[K, V]) reapEvery(interval time.Duration) {
+	defer t.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.done:
+			return
+		case <-ticker.C:
+			t.Reap()
+		}
+	}
+}
+
+// NewTTLTree creates a new, empty, TTLTree of the given degree.
+// When opts.ReapInterval is set, Close must be called to stop the background reaper.
+func NewTTLTree[K cmp.Ordered, V any](degree int, opts TTLOptions[K, V]) *TTLTree[K, V] {
+	t := &TTLTree[K, V]{
+		tree:     newBTree[K, ttlEntry[V]](degree),
+		expiries: newBTree[int64, []K](degree),
+		clock:    opts.Clock,
+		onExpire: opts.OnExpire,
+	}
+	if t.clock == nil {
+		t.clock = systemClock{}
+	}
+	if opts.ReapInterval > 0 {
+		t.done = make(chan struct{})
+		t.wg.Add(1)
+		go t.reapEvery(opts.ReapInterval)
+	}
+	return t
+}
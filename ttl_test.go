@@ -0,0 +1,121 @@
+package btree
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestTTLTree_GetExpires(t *testing.T) {
+	clock := NewManualClock(time.Unix(1000, 0))
+	var expired []string
+	tt := NewTTLTree[string, int](3, TTLOptions[string, int]{
+		Clock: clock,
+		OnExpire: func(key string, value *int) {
+			expired = append(expired, fmt.Sprintf("%s=%d", key, *value))
+		},
+	})
+	one, two := 1, 2
+	if err := tt.AddWithTTL("one", &one, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := tt.Add("two", &two); err != nil {
+		t.Fatal(err)
+	}
+	if ttl, ok := tt.TTL("one"); !ok || ttl != time.Second {
+		t.Errorf("expected ttl of %v, found %v %v", time.Second, ttl, ok)
+	}
+	if _, ok := tt.TTL("two"); ok {
+		t.Error("expected no ttl for key added without one")
+	}
+	clock.Advance(999 * time.Millisecond)
+	if v := tt.Get("one"); v == nil || *v != 1 {
+		t.Errorf("expected key to remain before its ttl, found %v", v)
+	}
+	clock.Advance(time.Millisecond)
+	if v := tt.Get("one"); v != nil {
+		t.Errorf("expected expired key to be nil, found %d", *v)
+	}
+	if tt.Count() != 1 || len(expired) != 1 || expired[0] != "one=1" {
+		t.Errorf("expected expired key removed and reported, found count %d, expired %v", tt.Count(), expired)
+	}
+	if v := tt.Get("two"); v == nil || *v != 2 {
+		t.Errorf("expected key without ttl to remain, found %v", v)
+	}
+}
+
+func TestTTLTree_Reap(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	var expired []int
+	tt := NewTTLTree[int, int](3, TTLOptions[int, int]{
+		Clock: clock,
+		OnExpire: func(key int, value *int) {
+			expired = append(expired, key)
+		},
+	})
+	for i := 0; i < 100; i++ {
+		v := i
+		// keys share expiry times, ten at each second
+		if err := tt.AddWithTTL(i, &v, time.Duration(i/10+1)*time.Second); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// replacing a key moves its expiry, removing a key drops it
+	_ = tt.AddWithTTL(5, new(int), time.Hour)
+	_ = tt.Add(6, new(int))
+	_ = tt.Remove(7)
+
+	clock.Advance(3 * time.Second)
+	if n := tt.Reap(); n != 27 {
+		t.Errorf("expected %d keys reaped, found %d", 27, n)
+	}
+	if len(expired) != 27 || tt.Count() != 72 {
+		t.Errorf("expected %d keys expired and %d remaining, found %d and %d", 27, 72, len(expired), tt.Count())
+	}
+	for _, k := range expired {
+		if k >= 30 || k == 5 || k == 6 || k == 7 {
+			t.Errorf("unexpected key %d expired", k)
+		}
+	}
+	if n := tt.Reap(); n != 0 {
+		t.Errorf("expected no keys reaped twice, found %d", n)
+	}
+	clock.Advance(time.Minute)
+	if n := tt.Reap(); n != 70 {
+		t.Errorf("expected %d keys reaped, found %d", 70, n)
+	}
+	var keys []int
+	for k := range tt.Keys(context.Background()) {
+		keys = append(keys, k)
+	}
+	if len(keys) != 2 || keys[0] != 5 || keys[1] != 6 {
+		t.Errorf("expected keys %v remaining, found %v", []int{5, 6}, keys)
+	}
+}
+
+func TestTTLTree_BackgroundReaper(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	reaped := make(chan int, 10)
+	tt := NewTTLTree[int, int](3, TTLOptions[int, int]{
+		Clock:        clock,
+		ReapInterval: time.Millisecond,
+		OnExpire: func(key int, value *int) {
+			reaped <- key
+		},
+	})
+	defer tt.Close()
+	_ = tt.AddWithTTL(1, new(int), time.Second)
+	clock.Advance(time.Second)
+	select {
+	case k := <-reaped:
+		if k != 1 {
+			t.Errorf("expected key %d reaped, found %d", 1, k)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected background reaper to remove expired key")
+	}
+	if tt.Count() != 0 {
+		t.Errorf("expected empty tree, found %d keys", tt.Count())
+	}
+}
@@ -0,0 +1,250 @@
+package btree
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Txn is a group of writes to a tree, applied all or nothing when committed.
+// Reads through the Txn see its own, uncommitted, writes.
+// A Txn is not safe for concurrent use.
+type Txn[K cmp.Ordered, V any] interface {
+	IsEmpty() bool
+	Keys(ctx context.Context) <-chan K
+	Get(key K) *V
+	Add(key K, value *V) error
+	Remove(key K) error
+	Count() int
+	// Commit applies all the writes to the tree.
+	// If any write fails, those already applied are undone, leaving the tree as it was.
+	// Watchers are sent the events of the writes once they are all applied, and none when the commit fails.
+	Commit() error
+	// Rollback discards all the writes.
+	Rollback() error
+}
+
+var errTxnDone = errors.New("transaction has already been committed or rolled back")
+
+// treeWriter is the part of a tree a transaction is committed to.
+type treeWriter[K cmp.Ordered, V any] interface {
+	Get(key K) *V
+	Add(key K, value *V) error
+	Remove(key K) error
+}
+
+// eventHolder is implemented by trees which can hold back their watch events while a transaction is committed,
+// so they are sent only when it succeeds, rather than followed by the events of undoing it.
+type eventHolder interface {
+	holdEvents()
+	releaseEvents(send bool)
+}
+
+// keyContainer is implemented by trees which can tell a key with a nil value from a missing key.
+type keyContainer[K cmp.Ordered] interface {
+	contains(key K) bool
+}
+
+// containsKey returns true if the given key is in the given tree.
+func containsKey[K cmp.Ordered, V any](tree treeWriter[K, V], key K) bool {
+	if kc, ok := tree.(keyContainer[K]); ok {
+		return kc.contains(key)
+	}
+	return tree.Get(key) != nil
+}
+
+// txnWrite is a single write in a transaction.
+type txnWrite[V any] struct {
+	value   *V
+	removed bool
+}
+
+// txnUndo restores a key to its state before a write was committed.
+type txnUndo[K cmp.Ordered, V any] struct {
+	key     K
+	existed bool
+	value   *V
+}
+
+type txn[K cmp.Ordered, V any] struct {
+	base   BTree[K, V]
+	writes map[K]txnWrite[V]
+	// apply calls the given function with the tree to commit the writes to,
+	// preventing readers of the base tree from seeing it until the function returns.
+	apply func(func(treeWriter[K, V]) error) error
+	done  bool
+}
+
+func (t *txn[K, V]) IsEmpty() bool {
+	return t.Count() == 0
+}
+
+func (t *txn[K, V]) Keys(ctx context.Context) <-chan K {
+	ch := make(chan K)
+	go func(ch chan<- K) {
+		defer close(ch)
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		base := t.base.Keys(ctx)
+		keys := t.writtenKeys()
+		bk, bok := <-base
+		for {
+			var next K
+			switch {
+			case len(keys) == 0 && !bok:
+				return
+			case len(keys) == 0 || bok && cmp.Compare(bk, keys[0]) < 0:
+				next = bk
+				bk, bok = <-base
+			default:
+				k := keys[0]
+				keys = keys[1:]
+				if bok && cmp.Compare(bk, k) == 0 {
+					// overwritten by the transaction
+					bk, bok = <-base
+				}
+				if t.writes[k].removed {
+					continue
+				}
+				next = k
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case ch <- next:
+			}
+		}
+	}(ch)
+	return ch
+}
+
+func (t *txn[K, V]) Get(key K) *V {
+	if w, ok := t.writes[key]; ok {
+		return w.value
+	}
+	return t.base.Get(key)
+}
+
+func (t *txn[K, V]) Add(key K, value *V) error {
+	if t.done {
+		return errTxnDone
+	}
+	t.writes[key] = txnWrite[V]{value: value}
+	return nil
+}
+
+func (t *txn[K, V]) Remove(key K) error {
+	if t.done {
+		return errTxnDone
+	}
+	if !t.contains(key) {
+		return fmt.Errorf("key %v is unknown", key)
+	}
+	t.writes[key] = txnWrite[V]{removed: true}
+	return nil
+}
+
+func (t *txn[K, V]) Count() int {
+	count := t.base.Count()
+	for k, w := range t.writes {
+		existed := containsKey[K, V](t.base, k)
+		if w.removed && existed {
+			count--
+		} else if !w.removed && !existed {
+			count++
+		}
+	}
+	return count
+}
+
+func (t *txn[K, V]) Commit() error {
+	if t.done {
+		return errTxnDone
+	}
+	t.done = true
+	return t.apply(func(tree treeWriter[K, V]) (err error) {
+		if h, ok := t.base.(eventHolder); ok {
+			h.holdEvents()
+			defer func() { h.releaseEvents(err == nil) }()
+		}
+		var undo []txnUndo[K, V]
+		for _, k := range t.writtenKeys() {
+			w := t.writes[k]
+			u := txnUndo[K, V]{key: k, existed: containsKey(tree, k)}
+			if u.existed {
+				u.value = tree.Get(k)
+			}
+			if w.removed {
+				if !u.existed {
+					// already gone
+					continue
+				}
+				err = tree.Remove(k)
+			} else {
+				err = tree.Add(k, w.value)
+			}
+			if err != nil {
+				rollback(tree, undo)
+				return err
+			}
+			undo = append(undo, u)
+		}
+		return nil
+	})
+}
+
+func (t *txn[K, V]) Rollback() error {
+	if t.done {
+		return errTxnDone
+	}
+	t.done = true
+	t.writes = nil
+	return nil
+}
+
+func (t *txn[K, V]) contains(key K) bool {
+	if w, ok := t.writes[key]; ok {
+		return !w.removed
+	}
+	return containsKey[K, V](t.base, key)
+}
+
+// writtenKeys returns the keys written in the transaction, in order.
+func (t *txn[K, V]) writtenKeys() []K {
+	keys := make([]K, 0, len(t.writes))
+	for k := range t.writes {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, cmp.Compare[K])
+	return keys
+}
+
+// undoer is implemented by tree writers whose failed commits are undone through another writer.
+type undoer[K cmp.Ordered, V any] interface {
+	undoWriter() treeWriter[K, V]
+}
+
+// rollback reverts the given, committed, writes in reverse order.
+func rollback[K cmp.Ordered, V any](tree treeWriter[K, V], undo []txnUndo[K, V]) {
+	if u, ok := tree.(undoer[K, V]); ok {
+		tree = u.undoWriter()
+	}
+	for i := len(undo) - 1; i >= 0; i-- {
+		u := undo[i]
+		if u.existed {
+			_ = tree.Add(u.key, u.value)
+		} else {
+			_ = tree.Remove(u.key)
+		}
+	}
+}
+
+func newTxn[K cmp.Ordered, V any](base BTree[K, V], apply func(func(treeWriter[K, V]) error) error) *txn[K, V] {
+	return &txn[K, V]{
+		base:   base,
+		writes: map[K]txnWrite[V]{},
+		apply:  apply,
+	}
+}
@@ -0,0 +1,391 @@
+package btree
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestTxn_ReadYourWrites(t *testing.T) {
+	bt := createTestTree(3, 10)
+	txn := bt.Begin()
+	v := "new"
+	if err := txn.Add(20, &v); err != nil {
+		t.Error(err)
+	}
+	if err := txn.Add(3, &v); err != nil {
+		t.Error(err)
+	}
+	if err := txn.Remove(5); err != nil {
+		t.Error(err)
+	}
+	if err := txn.Remove(5); err == nil {
+		t.Error("Expected error removing key already removed in transaction, got nil")
+	}
+	if err := txn.Remove(99); err == nil {
+		t.Error("Expected error removing unknown key, got nil")
+	}
+
+	if got := txn.Get(20); got == nil || *got != v {
+		t.Errorf("expected transaction to see its own add of key %d, got %v", 20, got)
+	}
+	if got := txn.Get(3); got == nil || *got != v {
+		t.Errorf("expected transaction to see its own update of key %d, got %v", 3, got)
+	}
+	if got := txn.Get(5); got != nil {
+		t.Errorf("expected transaction to see its own removal of key %d, got %v", 5, *got)
+	}
+	if txn.Count() != 10 {
+		t.Errorf("expected %d entries in transaction, found %d", 10, txn.Count())
+	}
+	var keys []int
+	for k := range txn.Keys(context.Background()) {
+		keys = append(keys, k)
+	}
+	expect := []int{0, 1, 2, 3, 4, 6, 7, 8, 9, 20}
+	if len(keys) != len(expect) {
+		t.Fatalf("expected keys %v in transaction, found %v", expect, keys)
+	}
+	for i := range expect {
+		if keys[i] != expect[i] {
+			t.Errorf("expected key %d at %d, found %d", expect[i], i, keys[i])
+		}
+	}
+
+	// the tree is unchanged until commit
+	if bt.Get(20) != nil || bt.Get(5) == nil || *bt.Get(3) != "-3-" {
+		t.Error("unexpected change to tree before commit")
+	}
+	if err := txn.Commit(); err != nil {
+		t.Error(err)
+	}
+	if bt.Get(20) == nil || bt.Get(5) != nil || *bt.Get(3) != v {
+		t.Error("expected transaction writes in tree after commit")
+	}
+	if err := validateTree(bt); err != nil {
+		t.Error(err)
+	}
+	if err := txn.Commit(); err == nil {
+		t.Error("Expected error committing a transaction twice, got nil")
+	}
+	if err := txn.Add(30, &v); err == nil {
+		t.Error("Expected error adding to committed transaction, got nil")
+	}
+}
+
+func TestTxn_Rollback(t *testing.T) {
+	bt := createTestTree(3, 10)
+	before := bt.rootnode.String()
+	txn := bt.Begin()
+	for i := 0; i < 10; i += 2 {
+		if err := txn.Remove(i); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := txn.Rollback(); err != nil {
+		t.Error(err)
+	}
+	if bt.rootnode.String() != before {
+		t.Errorf("expected tree unchanged by rollback")
+	}
+	if err := txn.Commit(); err == nil {
+		t.Error("Expected error committing a rolled back transaction, got nil")
+	}
+}
+
+type failingWriter struct {
+	treeWriter[int, string]
+	failKey int
+}
+
+func (fw failingWriter) Add(key int, value *string) error {
+	if key == fw.failKey {
+		return errors.New("failed")
+	}
+	return fw.treeWriter.Add(key, value)
+}
+
+func TestTxn_CommitFailure(t *testing.T) {
+	bt := createTestTree(3, 10)
+	txn := newTxn[int, string](bt, func(commit func(treeWriter[int, string]) error) error {
+		return commit(failingWriter{treeWriter: bt, failKey: 7})
+	})
+	v := "new"
+	for _, k := range []int{2, 5, 6, 7, 8, 15} {
+		if err := txn.Add(k, &v); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := txn.Remove(1); err != nil {
+		t.Error(err)
+	}
+	if err := txn.Commit(); err == nil {
+		t.Error("Expected error from failed commit, got nil")
+	}
+	if err := checkContains(bt, 10); err != nil {
+		t.Error(err)
+	}
+	if bt.Count() != 10 {
+		t.Errorf("expected %d entries after failed commit, found %d", 10, bt.Count())
+	}
+	if bt.Get(15) != nil {
+		t.Errorf("expected key %d removed by failed commit", 15)
+	}
+	if err := validateTree(bt); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTxn_Isolation(t *testing.T) {
+	count := 100
+	ct := NewConcurrentBTree[int, string](createTestTree(3, count))
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if c := ct.Count(); c != count {
+				t.Errorf("reader saw a half applied transaction, %d entries", c)
+				return
+			}
+		}
+	}()
+	// move each key to a new key
+	for i := 0; i < count; i++ {
+		txn := ct.Begin()
+		v := txn.Get(i)
+		if err := txn.Remove(i); err != nil {
+			t.Error(err)
+		}
+		if err := txn.Add(i+count, v); err != nil {
+			t.Error(err)
+		}
+		if err := txn.Commit(); err != nil {
+			t.Error(err)
+		}
+	}
+	close(done)
+	wg.Wait()
+	if v := ct.Get(count); v == nil || *v != "-0-" {
+		t.Errorf("expected moved value for key %d, got %v", count, v)
+	}
+}
+
+func TestTxn_WAL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tree.wal")
+	wt, err := OpenWAL[int, string](path, NewBTree[int, string](3), WALOptions{Sync: SyncEveryOp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fillTree(wt, 5); err != nil {
+		t.Error(err)
+	}
+	fi, _ := os.Stat(path)
+	beforeTxn := fi.Size()
+	txn := wt.Begin()
+	v := "moved"
+	if err := txn.Remove(1); err != nil {
+		t.Error(err)
+	}
+	if err := txn.Add(10, &v); err != nil {
+		t.Error(err)
+	}
+	if err := txn.Commit(); err != nil {
+		t.Error(err)
+	}
+	if err := wt.Close(); err != nil {
+		t.Error(err)
+	}
+
+	bt := NewBTree[int, string](3)
+	wt, err = OpenWAL[int, string](path, bt, WALOptions{Sync: SyncEveryOp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bt.Get(1) != nil || bt.Get(10) == nil {
+		t.Error("expected committed transaction replayed from log")
+	}
+	wt.Close()
+
+	// lose the end of the transaction, it must not be replayed in part.
+	data, _ := os.ReadFile(path)
+	if err := os.WriteFile(path, data[:len(data)-1], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	bt = NewBTree[int, string](3)
+	wt, err = OpenWAL[int, string](path, bt, WALOptions{Sync: SyncEveryOp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.Close()
+	if bt.Get(1) == nil || bt.Get(10) != nil {
+		t.Error("expected incomplete transaction discarded from log")
+	}
+	fi, _ = os.Stat(path)
+	if fi.Size() != beforeTxn {
+		t.Errorf("expected log truncated to %d bytes, found %d", beforeTxn, fi.Size())
+	}
+}
+
+type failingTree struct {
+	BTree[int, string]
+	failKey int
+}
+
+func (ft failingTree) Add(key int, value *string) error {
+	if key == ft.failKey {
+		return errors.New("failed")
+	}
+	return ft.BTree.Add(key, value)
+}
+
+func TestTxn_WALCommitFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tree.wal")
+	wt, err := OpenWAL[int, string](path, failingTree{BTree: NewBTree[int, string](3), failKey: 7}, WALOptions{Sync: SyncEveryOp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fillTree(wt, 5); err != nil {
+		t.Error(err)
+	}
+	fi, _ := os.Stat(path)
+	beforeTxn := fi.Size()
+	txn := wt.Begin()
+	v := "new"
+	for _, k := range []int{2, 6, 7} {
+		if err := txn.Add(k, &v); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := txn.Commit(); err == nil {
+		t.Error("Expected error from failed commit, got nil")
+	}
+	fi, _ = os.Stat(path)
+	if fi.Size() != beforeTxn {
+		t.Errorf("expected log cut back to %d bytes after failed commit, found %d", beforeTxn, fi.Size())
+	}
+	if err := wt.Add(20, &v); err != nil {
+		t.Error(err)
+	}
+	if err := wt.Close(); err != nil {
+		t.Error(err)
+	}
+
+	bt := NewBTree[int, string](3)
+	wt, err = OpenWAL[int, string](path, bt, WALOptions{Sync: SyncEveryOp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.Close()
+	if bt.Get(6) != nil || *bt.Get(2) == v {
+		t.Error("expected failed transaction not replayed")
+	}
+	if bt.Get(20) == nil {
+		t.Errorf("expected key %d, written after the failed transaction, replayed", 20)
+	}
+}
+
+func TestTxn_WALReplayUnendedTxn(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tree.wal")
+	v := "value"
+	var data []byte
+	for _, rec := range []walRecord[int, string]{
+		{Op: walTxnBegin},
+		{Op: walAdd, Key: 1, Value: &v, HasValue: true},
+		// the first transaction was never ended
+		{Op: walTxnBegin},
+		{Op: walAdd, Key: 2, Value: &v, HasValue: true},
+		{Op: walTxnEnd},
+		{Op: walAdd, Key: 3, Value: &v, HasValue: true},
+	} {
+		b, err := encodeWALRecord(rec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data = append(data, b...)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	bt := NewBTree[int, string](3)
+	wt, err := OpenWAL[int, string](path, bt, WALOptions{Sync: SyncEveryOp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.Close()
+	if bt.Get(1) != nil {
+		t.Errorf("expected key %d, of the unended transaction, not replayed", 1)
+	}
+	if bt.Get(2) == nil || bt.Get(3) == nil {
+		t.Error("expected records after the unended transaction replayed")
+	}
+}
+
+// hookTree calls after with each key added to it.
+type hookTree struct {
+	*bTree[int, string]
+	after func(key int)
+}
+
+func (ht hookTree) Add(key int, value *string) error {
+	err := ht.bTree.Add(key, value)
+	ht.after(key)
+	return err
+}
+
+func TestTxn_WALLogFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tree.wal")
+	var w *walTree[int, string]
+	var readOnly *os.File
+	bt := newBTree[int, string](3)
+	wt, err := OpenWAL[int, string](path, hookTree{bTree: bt, after: func(key int) {
+		if key == 6 && readOnly == nil {
+			// a read only handle fails the logging of the next write
+			readOnly, _ = os.Open(path)
+			w.file, readOnly = readOnly, w.file
+		}
+	}}, WALOptions{Sync: SyncEveryOp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w = wt.(*walTree[int, string])
+	if err := fillTree(wt, 5); err != nil {
+		t.Error(err)
+	}
+	before := bt.rootnode.String()
+	watcher := wt.Watch(0, 10, WatchOptions{})
+	defer watcher.Close()
+	txn := wt.Begin()
+	v := "new"
+	for _, k := range []int{2, 6, 7} {
+		if err := txn.Add(k, &v); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := txn.Commit(); err == nil {
+		t.Error("Expected error from commit failing to log, got nil")
+	}
+	if readOnly == nil {
+		t.Fatal("expected log to fail during the commit")
+	}
+	w.file.Close()
+	w.file = readOnly
+	if after := bt.rootnode.String(); after != before {
+		t.Errorf("expected tree unchanged by failed commit, found %s, was %s", after, before)
+	}
+	if *bt.Get(2) == v || bt.Get(6) != nil {
+		t.Error("expected failed commit undone")
+	}
+	if n := len(watcher.Events()); n != 0 {
+		t.Errorf("expected no events from a failed commit, found %d", n)
+	}
+}
@@ -0,0 +1,140 @@
+package btree
+
+import (
+	"cmp"
+)
+
+// UpdateAction is the change an Update function makes to its key.
+type UpdateAction int
+
+const (
+	// UpdateKeep leaves the key as it is.
+	UpdateKeep UpdateAction = iota
+	// UpdateSet sets the key to the returned value, adding the key if it does not exist.
+	UpdateSet
+	// UpdateRemove removes the key, if it exists.
+	UpdateRemove
+)
+
+// UpdateFunc is given the current value of a key, and whether the key exists,
+// and returns the new value with the action to take.
+type UpdateFunc[V any] func(old *V, exists bool) (*V, UpdateAction)
+
+// Update reads, and then modifies, the given key with a single descent of the tree.
+// Only inserts which split a node, and removals which empty a node, descend again to rebalance the tree.
+func (b *bTree[K, V]) Update(key K, fn UpdateFunc[V]) error {
+	leaf, _, e := b.leafFor(key)
+	if e == nil {
+		_, e = leaf.keyIndex(key)
+	} else {
+		// found in a parent node
+		leaf = nil
+	}
+	exists := e != nil
+	var old *V
+	if exists {
+		old = e.Value
+	}
+	value, action := fn(old, exists)
+	switch action {
+	case UpdateSet:
+		if exists {
+			e.Value = value
+			b.usage.written(old, value, true)
+			b.notifyWrite(key, value, old, true)
+			return nil
+		}
+		if len(leaf.Entries) < b.degree-1 {
+			leaf.Insert(key, value)
+			b.usage.written(nil, value, false)
+			b.notifyWrite(key, value, nil, false)
+			return nil
+		}
+		return b.Add(key, value)
+	case UpdateRemove:
+		if !exists {
+			return nil
+		}
+		if leaf != nil && len(leaf.Entries) > 1 {
+			_, _ = leaf.Delete(key)
+			b.usage.removed(old)
+			b.notifyRemove(key, old)
+			return nil
+		}
+		return b.Remove(key)
+	}
+	return nil
+}
+
+// CompareAndSwap sets the given key to new, only if it exists with a value equal to old.
+// Values are compared with eq, or by pointer if eq is nil. Returns true if the value was swapped.
+func (b *bTree[K, V]) CompareAndSwap(key K, old, new *V, eq func(a, b *V) bool) (bool, error) {
+	return compareAndSwap(b.Update, key, old, new, eq)
+}
+
+// CompareAndDelete removes the given key, only if it exists with a value equal to old.
+// Values are compared with eq, or by pointer if eq is nil. Returns true if the key was removed.
+func (b *bTree[K, V]) CompareAndDelete(key K, old *V, eq func(a, b *V) bool) (bool, error) {
+	return compareAndDelete(b.Update, key, old, eq)
+}
+
+// GetOrCompute returns the value of the given key, if it exists.
+// Otherwise the key is added with the value returned by build, with the same descent of the tree as the lookup.
+// If build returns an error, nothing is added and the error is returned.
+func (b *bTree[K, V]) GetOrCompute(key K, build func() (*V, error)) (*V, error) {
+	return getOrCompute(b.Update, key, build)
+}
+
+func compareAndSwap[K cmp.Ordered, V any](update func(K, UpdateFunc[V]) error, key K, old, new *V, eq func(a, b *V) bool) (bool, error) {
+	swapped := false
+	err := update(key, func(cur *V, exists bool) (*V, UpdateAction) {
+		if !exists || !equalValues(cur, old, eq) {
+			return cur, UpdateKeep
+		}
+		swapped = true
+		return new, UpdateSet
+	})
+	return swapped && err == nil, err
+}
+
+func compareAndDelete[K cmp.Ordered, V any](update func(K, UpdateFunc[V]) error, key K, old *V, eq func(a, b *V) bool) (bool, error) {
+	deleted := false
+	err := update(key, func(cur *V, exists bool) (*V, UpdateAction) {
+		if !exists || !equalValues(cur, old, eq) {
+			return cur, UpdateKeep
+		}
+		deleted = true
+		return nil, UpdateRemove
+	})
+	return deleted && err == nil, err
+}
+
+func equalValues[V any](a, b *V, eq func(a, b *V) bool) bool {
+	if eq == nil {
+		return a == b
+	}
+	return eq(a, b)
+}
+
+func getOrCompute[K cmp.Ordered, V any](update func(K, UpdateFunc[V]) error, key K, build func() (*V, error)) (*V, error) {
+	var value *V
+	var buildErr error
+	err := update(key, func(cur *V, exists bool) (*V, UpdateAction) {
+		if exists {
+			value = cur
+			return cur, UpdateKeep
+		}
+		value, buildErr = build()
+		if buildErr != nil {
+			return nil, UpdateKeep
+		}
+		return value, UpdateSet
+	})
+	if buildErr != nil {
+		return nil, buildErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
@@ -0,0 +1,194 @@
+package btree
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestBTree_Update(t *testing.T) {
+	bt := createTestTree(3, 10)
+	err := bt.Update(3, func(old *string, exists bool) (*string, UpdateAction) {
+		if !exists || *old != "-3-" {
+			t.Errorf("expected existing value for key %d, got %v, %v", 3, old, exists)
+		}
+		v := *old + "updated"
+		return &v, UpdateSet
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	if v := bt.Get(3); v == nil || *v != "-3-updated" {
+		t.Errorf("expected updated value for key %d, got %v", 3, v)
+	}
+	_ = bt.Update(20, func(old *string, exists bool) (*string, UpdateAction) {
+		if exists {
+			t.Errorf("expected key %d not to exist", 20)
+		}
+		v := "new"
+		return &v, UpdateSet
+	})
+	_ = bt.Update(5, func(old *string, exists bool) (*string, UpdateAction) {
+		return nil, UpdateRemove
+	})
+	_ = bt.Update(6, func(old *string, exists bool) (*string, UpdateAction) {
+		return nil, UpdateKeep
+	})
+	if bt.Get(20) == nil || bt.Get(5) != nil || bt.Get(6) == nil {
+		t.Error("unexpected tree contents after updates")
+	}
+	if err := validateTree(bt); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBTree_Update_Random(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, degree := range []int{3, 4, 9} {
+		bt := NewBTree[int, string](degree)
+		model := map[int]bool{}
+		for i := 0; i < 5000; i++ {
+			key := rnd.Intn(300)
+			action := UpdateAction(rnd.Intn(3))
+			_ = bt.Update(key, func(old *string, exists bool) (*string, UpdateAction) {
+				if exists != model[key] {
+					t.Fatalf("degree %d: expected key %d exists %v", degree, key, model[key])
+				}
+				v := "-" + strconv.Itoa(key) + "-"
+				return &v, action
+			})
+			switch action {
+			case UpdateSet:
+				model[key] = true
+			case UpdateRemove:
+				delete(model, key)
+			}
+		}
+		if err := compareToModel(bt, model); err != nil {
+			t.Errorf("degree %d: %v", degree, err)
+		}
+	}
+}
+
+func TestBTree_CompareAndSwap(t *testing.T) {
+	bt := createTestTree(3, 10)
+	cur := bt.Get(4)
+	stale := "-4-"
+	v := "new"
+	if ok, err := bt.CompareAndSwap(4, &stale, &v, nil); ok || err != nil {
+		t.Errorf("expected no swap of a different pointer, got %v, %v", ok, err)
+	}
+	if ok, _ := bt.CompareAndSwap(4, &stale, &v, func(a, b *string) bool { return *a == *b }); !ok {
+		t.Error("expected swap of an equal value")
+	}
+	if ok, _ := bt.CompareAndSwap(4, cur, &v, nil); ok {
+		t.Error("expected no swap of an old value")
+	}
+	if ok, _ := bt.CompareAndSwap(99, nil, &v, nil); ok {
+		t.Error("expected no swap of an unknown key")
+	}
+	if ok, _ := bt.CompareAndDelete(4, cur, nil); ok {
+		t.Error("expected no delete of an old value")
+	}
+	if ok, _ := bt.CompareAndDelete(4, &v, nil); !ok {
+		t.Error("expected delete of the current value")
+	}
+	if bt.Get(4) != nil {
+		t.Errorf("expected key %d deleted", 4)
+	}
+}
+
+func TestBTree_CompareAndSwap_Concurrent(t *testing.T) {
+	ct := NewConcurrentBTree[string, int](NewBTree[string, int](3))
+	zero := 0
+	_ = ct.Add("counter", &zero)
+	var wg sync.WaitGroup
+	workers, increments := 8, 200
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < increments; {
+				old := ct.Get("counter")
+				v := *old + 1
+				if ok, err := ct.CompareAndSwap("counter", old, &v, nil); err != nil {
+					t.Error(err)
+					return
+				} else if ok {
+					n++
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if v := ct.Get("counter"); *v != workers*increments {
+		t.Errorf("expected counter %d, found %d", workers*increments, *v)
+	}
+}
+
+func TestBTree_GetOrCompute(t *testing.T) {
+	bt := createTestTree(3, 10)
+	built := 0
+	build := func() (*string, error) {
+		built++
+		v := "built"
+		return &v, nil
+	}
+	if v, err := bt.GetOrCompute(4, build); err != nil || *v != "-4-" {
+		t.Errorf("expected existing value for key %d, got %v, %v", 4, v, err)
+	}
+	if v, err := bt.GetOrCompute(20, build); err != nil || *v != "built" {
+		t.Errorf("expected built value for key %d, got %v, %v", 20, v, err)
+	}
+	if v := bt.Get(20); v == nil || *v != "built" {
+		t.Errorf("expected key %d added, got %v", 20, v)
+	}
+	if built != 1 {
+		t.Errorf("expected build called once, called %d times", built)
+	}
+	_, err := bt.GetOrCompute(21, func() (*string, error) {
+		return nil, fmt.Errorf("failed")
+	})
+	if err == nil || err.Error() != "failed" {
+		t.Errorf("expected builder error, got %v", err)
+	}
+	if bt.Get(21) != nil || bt.Count() != 11 {
+		t.Errorf("expected nothing added on builder error")
+	}
+	if err := validateTree(bt); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBTree_GetOrCompute_Concurrent(t *testing.T) {
+	ct := NewConcurrentBTree[int, int](NewBTree[int, int](3))
+	var mu sync.Mutex
+	built := map[int]int{}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := 0; k < 100; k++ {
+				v, err := ct.GetOrCompute(k, func() (*int, error) {
+					mu.Lock()
+					defer mu.Unlock()
+					built[k]++
+					return &k, nil
+				})
+				if err != nil || *v != k {
+					t.Errorf("expected value %d, got %v, %v", k, v, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	for k := 0; k < 100; k++ {
+		if built[k] != 1 {
+			t.Errorf("expected key %d built once, built %d times", k, built[k])
+		}
+	}
+}
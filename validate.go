@@ -0,0 +1,144 @@
+package btree
+
+import (
+	"cmp"
+	"fmt"
+)
+
+// Validate checks the structure of the tree, returning an error describing the first fault found, and the path to its node.
+// The keys must be in order, within the keys of the parent entries either side of their node,
+// every parent must have one more child than entries, every leaf must be at the same depth,
+// and every node, other than the root, must hold from 1 to degree - 1 entries.
+// Paths name the index of each child from the root, such as "root/2/0".
+// Every node is read, so it is intended for debugging and tests.
+func (b *nodeTree[K, S]) Validate() error {
+	if !b.rootnode.IsLeaf() && len(b.rootnode.Entries) == 0 {
+		return fmt.Errorf("node at root has %d children, and no entries", len(b.rootnode.Children))
+	}
+	return b.rootnode.validate("root", b.degree, b.rootnode.height(), keyBounds[K]{}, true)
+}
+
+// validate checks this node, of the given height, and its children. Its keys must lie within the given bounds.
+func (n *node[K, S]) validate(path string, degree, height int, bounds keyBounds[K], root bool) error {
+	if len(n.Entries) > degree-1 || !root && len(n.Entries) == 0 {
+		return fmt.Errorf("node at %s has %d entries, when it must have from 1 to %d", path, len(n.Entries), degree-1)
+	}
+	for i, e := range n.Entries {
+		if i > 0 && !cmp.Less(n.Entries[i-1].Key, e.Key) {
+			return fmt.Errorf("node at %s has key %v at index %d, out of order after %v", path, e.Key, i, n.Entries[i-1].Key)
+		}
+		if !bounds.contains(e.Key) {
+			return fmt.Errorf("node at %s has key %v, outside the keys of its parent entries %s", path, e.Key, bounds)
+		}
+	}
+	if n.IsLeaf() {
+		if height != 0 {
+			return fmt.Errorf("leaf at %s is %d levels above the leftmost leaf", path, height)
+		}
+		return nil
+	}
+	if height == 0 {
+		return fmt.Errorf("node at %s has children, below the level of the leftmost leaf", path)
+	}
+	if len(n.Children) != len(n.Entries)+1 {
+		return fmt.Errorf("node at %s has %d children, when it has %d entries", path, len(n.Children), len(n.Entries))
+	}
+	for i := range n.Children {
+		cb := bounds
+		if i > 0 {
+			cb.lo, cb.hasLo = n.Entries[i-1].Key, true
+		}
+		if i < len(n.Entries) {
+			cb.hi, cb.hasHi = n.Entries[i].Key, true
+		}
+		if err := n.Children[i].validate(fmt.Sprintf("%s/%d", path, i), degree, height-1, cb, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (kb keyBounds[K]) String() string {
+	lo, hi := "-", "-"
+	if kb.hasLo {
+		lo = fmt.Sprint(kb.lo)
+	}
+	if kb.hasHi {
+		hi = fmt.Sprint(kb.hi)
+	}
+	return fmt.Sprintf("(%s, %s)", lo, hi)
+}
+
+// Validate checks the structure of the tree, returning an error describing the first fault found, and the path to its node.
+// The keys must be in order, within the separators either side of their node,
+// every parent must have one more child than keys, every leaf must be at the same depth, and linked to the next in order,
+// and every node, other than the root, must hold from (degree - 1) / 2 to degree - 1 keys.
+// Paths name the index of each child from the root, such as "root/2/0".
+// Every node is read, so it is intended for debugging and tests.
+func (b *bPlusTree[K, V]) Validate() error {
+	var leaves []*bpNode[K, V]
+	if err := b.validate(b.root, "root", b.Depth(), keyBounds[K]{}, &leaves); err != nil {
+		return err
+	}
+	count := 0
+	leaf := b.firstLeaf()
+	for i, l := range leaves {
+		if leaf != l {
+			return fmt.Errorf("leaf %d, in key order, is not linked from the leaf before it", i)
+		}
+		count += len(l.keys)
+		leaf = leaf.next
+	}
+	if leaf != nil {
+		return fmt.Errorf("last leaf is linked to another leaf")
+	}
+	if count != b.count {
+		return fmt.Errorf("leaves hold %d keys, when the tree counts %d", count, b.count)
+	}
+	return nil
+}
+
+// validate checks the given node, of the given height, and its children, adding its leaves to leaves, in order.
+// Its keys must lie within the given bounds, the lower bound inclusive, as a separator is the first key of the child after it.
+func (b *bPlusTree[K, V]) validate(nd *bpNode[K, V], path string, height int, bounds keyBounds[K], leaves *[]*bpNode[K, V]) error {
+	if len(nd.keys) > b.degree-1 || nd != b.root && len(nd.keys) < b.minKeys() {
+		return fmt.Errorf("node at %s has %d keys, when it must have from %d to %d", path, len(nd.keys), b.minKeys(), b.degree-1)
+	}
+	for i, k := range nd.keys {
+		if i > 0 && !cmp.Less(nd.keys[i-1], k) {
+			return fmt.Errorf("node at %s has key %v at index %d, out of order after %v", path, k, i, nd.keys[i-1])
+		}
+		if bounds.hasLo && cmp.Less(k, bounds.lo) || bounds.hasHi && !cmp.Less(k, bounds.hi) {
+			return fmt.Errorf("node at %s has key %v, outside the separators of its parent %s", path, k, bounds)
+		}
+	}
+	if nd.isLeaf() {
+		if height != 0 {
+			return fmt.Errorf("leaf at %s is %d levels above the leftmost leaf", path, height)
+		}
+		if len(nd.values) != len(nd.keys) {
+			return fmt.Errorf("leaf at %s has %d values for %d keys", path, len(nd.values), len(nd.keys))
+		}
+		*leaves = append(*leaves, nd)
+		return nil
+	}
+	if height == 0 {
+		return fmt.Errorf("node at %s has children, below the level of the leftmost leaf", path)
+	}
+	if len(nd.children) != len(nd.keys)+1 {
+		return fmt.Errorf("node at %s has %d children, when it has %d keys", path, len(nd.children), len(nd.keys))
+	}
+	for i, child := range nd.children {
+		cb := bounds
+		if i > 0 {
+			cb.lo, cb.hasLo = nd.keys[i-1], true
+		}
+		if i < len(nd.keys) {
+			cb.hi, cb.hasHi = nd.keys[i], true
+		}
+		if err := b.validate(child, fmt.Sprintf("%s/%d", path, i), height-1, cb, leaves); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package btree
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestValidate_Random(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, degree := range []int{3, 4, 5, 10} {
+		trees := []BTree[string, int]{NewBTree[string, int](degree), NewBPlusTree[string, int](degree)}
+		for i := 0; i < 3000; i++ {
+			k := strconv.Itoa(rnd.Intn(400))
+			for _, tree := range trees {
+				if rnd.Intn(3) == 0 {
+					_ = tree.Remove(k)
+				} else {
+					_ = tree.Add(k, &i)
+				}
+			}
+			if i%100 != 0 {
+				continue
+			}
+			for _, tree := range trees {
+				if err := tree.Validate(); err != nil {
+					t.Fatalf("degree %d: %v", degree, err)
+				}
+			}
+		}
+	}
+}
+
+func TestValidate_Faults(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		corrupt func(b *bTree[int, string])
+		expect  string
+	}{
+		// the tree of keys 0 to 19 is three levels deep, the root with two children, of two and three children
+		{"order", func(b *bTree[int, string]) {
+			leaf := &b.rootnode.Children[1].Children[2].Children[1]
+			leaf.Entries[0], leaf.Entries[1] = leaf.Entries[1], leaf.Entries[0]
+		}, "node at root/1/2/1 has key"},
+		{"bounds", func(b *bTree[int, string]) {
+			b.rootnode.Children[0].Children[1].Entries[0].Key = 1000
+		}, "node at root/0/1 has key 1000, outside"},
+		{"empty", func(b *bTree[int, string]) {
+			b.rootnode.Children[1].Children[2].Entries = nil
+		}, "node at root/1/2 has 0 entries"},
+		{"full", func(b *bTree[int, string]) {
+			leaf := &b.rootnode.Children[1].Children[2].Children[1]
+			leaf.Entries = append(leaf.Entries, nodeEntry[int, *string]{Key: 100})
+		}, "node at root/1/2/1 has 3 entries"},
+		{"children", func(b *bTree[int, string]) {
+			b.rootnode.Children[1].Children = b.rootnode.Children[1].Children[:1]
+		}, "node at root/1 has 1 children"},
+		{"depth", func(b *bTree[int, string]) {
+			b.rootnode.Children[1].Children[1].Children = nil
+		}, "leaf at root/1/1 is 1 levels above"},
+	} {
+		b := createTestTree(3, 20)
+		if err := b.Validate(); err != nil {
+			t.Fatalf("%s: unexpected error before breaking tree %v", c.name, err)
+		}
+		c.corrupt(b)
+		err := b.Validate()
+		if err == nil || !strings.HasPrefix(err.Error(), c.expect) {
+			t.Errorf("%s: expected error starting %q, found %v", c.name, c.expect, err)
+		}
+	}
+}
+
+func TestValidate_BPlusTreeFaults(t *testing.T) {
+	b := newBPlusTree[int, string](4)
+	fillTree(b, 100)
+	if err := b.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	b.count++
+	if err := b.Validate(); err == nil || !strings.Contains(err.Error(), "when the tree counts") {
+		t.Errorf("expected count error, found %v", err)
+	}
+	b.count--
+	b.firstLeaf().next = nil
+	if err := b.Validate(); err == nil || !strings.Contains(err.Error(), "is not linked") {
+		t.Errorf("expected link error, found %v", err)
+	}
+}
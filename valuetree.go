@@ -0,0 +1,105 @@
+package btree
+
+import (
+	"cmp"
+	"context"
+	"log"
+)
+
+// ValueTree is an ordered map which stores its values in its nodes, rather than pointers to them.
+// Small values, such as numbers, need no allocation of their own, and a missing key is told apart from a zero value by Get.
+type ValueTree[K cmp.Ordered, V any] interface {
+	Degree() int
+	IsEmpty() bool
+	Count() int
+	// Set sets the given key to the given value.
+	Set(key K, value V)
+	// Get returns the value of the given key, and false if the key is unknown.
+	Get(key K) (V, bool)
+	// Delete removes the given key, returning false if it was unknown.
+	Delete(key K) bool
+	Keys(ctx context.Context) <-chan K
+	// Range calls fn with each key from lo to hi, inclusive, and its value, in order, until fn returns false.
+	Range(lo, hi K, fn func(key K, value V) bool)
+}
+
+// valueTree stores its values in the entries of its nodes, sharing the algorithms of a BTree, which stores pointers to them.
+type valueTree[K cmp.Ordered, V any] struct {
+	nodeTree[K, V]
+}
+
+func (t valueTree[K, V]) Degree() int {
+	return t.degree
+}
+
+func (t valueTree[K, V]) IsEmpty() bool {
+	return t.rootnode.IsEmpty()
+}
+
+// Count returns the number of keys, the entries counted by the usage of the tree.
+func (t valueTree[K, V]) Count() int {
+	return int(t.usage.entries)
+}
+
+func (t *valueTree[K, V]) Set(key K, value V) {
+	t.insert(key, value)
+}
+
+func (t valueTree[K, V]) Get(key K) (V, bool) {
+	if e := t.rootnode.Get(key); e != nil {
+		return e.Value, true
+	}
+	var zero V
+	return zero, false
+}
+
+func (t *valueTree[K, V]) Delete(key K) bool {
+	_, err := t.delete(key)
+	return err == nil
+}
+
+func (t valueTree[K, V]) Keys(ctx context.Context) <-chan K {
+	ch := make(chan K)
+	go func(ch chan<- K) {
+		defer close(ch)
+		t.rootnode.ascend(nil, false, func(e *nodeEntry[K, V]) bool {
+			select {
+			case <-ctx.Done():
+				return false
+			case ch <- e.Key:
+				return true
+			}
+		})
+	}(ch)
+	return ch
+}
+
+func (t valueTree[K, V]) Range(lo, hi K, fn func(key K, value V) bool) {
+	t.rootnode.ascend(&lo, true, func(e *nodeEntry[K, V]) bool {
+		return !cmp.Less(hi, e.Key) && fn(e.Key, e.Value)
+	})
+}
+
+// set sets the key to the value, returning true if the key was added.
+func (t *valueTree[K, V]) set(key K, value V) bool {
+	count := t.usage.entries
+	t.insert(key, value)
+	return t.usage.entries > count
+}
+
+// NewValueTree creates a new, empty, ValueTree of the given degree.
+func NewValueTree[K cmp.Ordered, V any](degree int) ValueTree[K, V] {
+	return newValueTree[K, V](degree)
+}
+
+func newValueTree[K cmp.Ordered, V any](degree int) *valueTree[K, V] {
+	if degree < 2 {
+		log.Fatalf("degree must be >= 2")
+	}
+	// the values are not sized, as a ValueTree does not report its memory usage, only its entries are counted.
+	usage := &memoryUsage[V]{sizer: func(V) int64 { return 0 }}
+	usage.reset()
+	return &valueTree[K, V]{
+		nodeTree: nodeTree[K, V]{rootnode: &node[K, V]{}, degree: degree, usage: usage},
+	}
+}
@@ -0,0 +1,78 @@
+package btree
+
+import (
+	"context"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestValueTree_SetGet(t *testing.T) {
+	vt := NewValueTree[string, int](3)
+	vt.Set("zero", 0)
+	vt.Set("one", 1)
+	if v, ok := vt.Get("zero"); !ok || v != 0 {
+		t.Errorf("expected stored zero value, found %d, %v", v, ok)
+	}
+	if _, ok := vt.Get("two"); ok {
+		t.Error("expected missing key not to be found")
+	}
+	vt.Set("one", 11)
+	if v, _ := vt.Get("one"); v != 11 || vt.Count() != 2 {
+		t.Errorf("expected value replaced, found %d of %d keys", v, vt.Count())
+	}
+	if !vt.Delete("one") || vt.Delete("one") {
+		t.Error("expected delete to report only known keys")
+	}
+}
+
+func TestValueTree_Random(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, degree := range []int{3, 4, 5, 10} {
+		vt := NewValueTree[int, int](degree)
+		model := map[int]int{}
+		for i := 0; i < 10000; i++ {
+			k := rnd.Intn(500)
+			if rnd.Intn(3) > 0 {
+				vt.Set(k, i)
+				model[k] = i
+			} else {
+				_, ok := model[k]
+				if vt.Delete(k) != ok {
+					t.Fatalf("degree %d: unexpected delete result for key %d", degree, k)
+				}
+				delete(model, k)
+			}
+		}
+		if vt.Count() != len(model) {
+			t.Errorf("degree %d: expected %d keys, found %d", degree, len(model), vt.Count())
+		}
+		for k, v := range model {
+			if found, ok := vt.Get(k); !ok || found != v {
+				t.Fatalf("degree %d: expected key %d value %d, found %d, %v", degree, k, v, found, ok)
+			}
+		}
+		var keys []int
+		for k := range vt.Keys(context.Background()) {
+			keys = append(keys, k)
+		}
+		if !slices.IsSorted(keys) || len(keys) != len(model) {
+			t.Errorf("degree %d: expected %d keys in order, found %v", degree, len(model), keys)
+		}
+	}
+}
+
+func TestValueTree_Range(t *testing.T) {
+	vt := NewValueTree[int, int](3)
+	for i := 0; i < 100; i++ {
+		vt.Set(i, i*i)
+	}
+	var found []int
+	vt.Range(10, 13, func(k, v int) bool {
+		found = append(found, v)
+		return true
+	})
+	if expect := []int{100, 121, 144, 169}; !slices.Equal(found, expect) {
+		t.Errorf("expected values %v, found %v", expect, found)
+	}
+}
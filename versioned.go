@@ -0,0 +1,281 @@
+package btree
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"sync"
+)
+
+// viewScanSize is the number of keys a View reads each time it takes the read lock, while scanning.
+// Between each group of keys the lock is released, so long scans do not hold up writers.
+const viewScanSize = 256
+
+// VersionedTree is a tree in which every write creates a new version of the tree, numbered in sequence.
+// Views of the tree see it as it was at a given version, unaffected by any later writes,
+// so long running reads see consistent data while writes continue.
+// Versions older than the oldest open view are pruned by CollectGarbage.
+// A VersionedTree is safe for concurrent use.
+type VersionedTree[K cmp.Ordered, V any] struct {
+	tree    *bTree[K, versionChain[V]]
+	mu      sync.RWMutex
+	version uint64
+	// horizon is the oldest version which has not been pruned.
+	horizon uint64
+	views   map[*View[K, V]]struct{}
+}
+
+// version is the value of a key from a given version of the tree.
+type version[V any] struct {
+	seq     uint64
+	value   *V
+	removed bool
+}
+
+// versionChain holds all the versions of a key, oldest first.
+type versionChain[V any] []version[V]
+
+// at returns the version visible at the given version of the tree, or nil if the key did not exist.
+func (vc versionChain[V]) at(seq uint64) *version[V] {
+	for i := len(vc) - 1; i >= 0; i-- {
+		if vc[i].seq <= seq {
+			if vc[i].removed {
+				return nil
+			}
+			return &vc[i]
+		}
+	}
+	return nil
+}
+
+// Version returns the current version of the tree.
+func (vt *VersionedTree[K, V]) Version() uint64 {
+	vt.mu.RLock()
+	defer vt.mu.RUnlock()
+	return vt.version
+}
+
+// Get returns the current value of the given key.
+func (vt *VersionedTree[K, V]) Get(key K) *V {
+	vt.mu.RLock()
+	defer vt.mu.RUnlock()
+	return vt.get(key, vt.version)
+}
+
+// Add sets the given key to the given value, returning the new version of the tree.
+func (vt *VersionedTree[K, V]) Add(key K, value *V) (uint64, error) {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	vt.version++
+	vt.write(key, version[V]{seq: vt.version, value: value})
+	return vt.version, nil
+}
+
+// Remove removes the given key, returning the new version of the tree.
+func (vt *VersionedTree[K, V]) Remove(key K) (uint64, error) {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	ne := vt.tree.rootnode.Get(key)
+	if ne == nil || ne.Value.at(vt.version) == nil {
+		return vt.version, fmt.Errorf("key %v is unknown", key)
+	}
+	vt.version++
+	vt.write(key, version[V]{seq: vt.version, removed: true})
+	return vt.version, nil
+}
+
+// View opens a view of the tree at the given version.
+// The version must be no older than the oldest version not yet pruned.
+// The view must be closed once finished with, to allow the versions it sees to be pruned.
+func (vt *VersionedTree[K, V]) View(version uint64) (*View[K, V], error) {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	if version > vt.version {
+		return nil, fmt.Errorf("version %d does not exist yet, current version is %d", version, vt.version)
+	}
+	if version < vt.horizon {
+		return nil, fmt.Errorf("version %d has been pruned, oldest version is %d", version, vt.horizon)
+	}
+	return vt.openView(version), nil
+}
+
+// Current opens a view of the current version of the tree.
+func (vt *VersionedTree[K, V]) Current() *View[K, V] {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	return vt.openView(vt.version)
+}
+
+// CollectGarbage prunes all the versions which can no longer be seen, by any open view or the current version.
+// Returns the number of versions pruned.
+func (vt *VersionedTree[K, V]) CollectGarbage() int {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	oldest := vt.version
+	for v := range vt.views {
+		oldest = min(oldest, v.version)
+	}
+	vt.horizon = oldest
+
+	pruned := 0
+	var gone []K
+	vt.tree.rootnode.ascend(nil, false, func(e *nodeEntry[K, *versionChain[V]]) bool {
+		chain := *e.Value
+		// keep the version visible at the oldest version, and all those after it
+		keep := -1
+		for i := len(chain) - 1; i >= 0; i-- {
+			if chain[i].seq <= oldest {
+				keep = i
+				break
+			}
+		}
+		if keep < 0 {
+			// every version is newer than the oldest
+			return true
+		}
+		if keep == len(chain)-1 && chain[keep].removed {
+			// the only version left is a removal, the key can go entirely
+			gone = append(gone, e.Key)
+			pruned += len(chain)
+			return true
+		}
+		if chain[keep].removed {
+			keep++
+		}
+		if keep > 0 {
+			pruned += keep
+			*e.Value = append(versionChain[V]{}, chain[keep:]...)
+		}
+		return true
+	})
+	for _, k := range gone {
+		_ = vt.tree.Remove(k)
+	}
+	return pruned
+}
+
+func (vt *VersionedTree[K, V]) get(key K, seq uint64) *V {
+	ne := vt.tree.rootnode.Get(key)
+	if ne == nil {
+		return nil
+	}
+	if v := ne.Value.at(seq); v != nil {
+		return v.value
+	}
+	return nil
+}
+
+// write appends the given version to the keys chain of versions.
+// Caller must hold the write lock.
+func (vt *VersionedTree[K, V]) write(key K, v version[V]) {
+	if ne := vt.tree.rootnode.Get(key); ne != nil {
+		*ne.Value = append(*ne.Value, v)
+		return
+	}
+	_ = vt.tree.Add(key, &versionChain[V]{v})
+}
+
+// openView creates and registers a new view.
+// Caller must hold the write lock.
+func (vt *VersionedTree[K, V]) openView(version uint64) *View[K, V] {
+	v := &View[K, V]{tree: vt, version: version}
+	vt.views[v] = struct{}{}
+	return v
+}
+
+func (vt *VersionedTree[K, V]) closeView(v *View[K, V]) {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	delete(vt.views, v)
+	v.closed = true
+}
+
+// View is a read only view of a VersionedTree, as it was at a given version.
+// Once closed, the versions it sees may be pruned, so it reads as empty.
+type View[K cmp.Ordered, V any] struct {
+	tree    *VersionedTree[K, V]
+	version uint64
+	// closed is set by Close, guarded by the lock of the tree.
+	closed bool
+}
+
+// Version returns the version of the tree the view sees.
+func (v *View[K, V]) Version() uint64 {
+	return v.version
+}
+
+// Get returns the value of the given key, as it was at the views version, or nil once the view is closed.
+func (v *View[K, V]) Get(key K) *V {
+	v.tree.mu.RLock()
+	defer v.tree.mu.RUnlock()
+	if v.closed {
+		return nil
+	}
+	return v.tree.get(key, v.version)
+}
+
+// Keys returns the keys present at the views version, in order.
+// The keys are read a group at a time, so writes to the tree may continue during the scan.
+// The channel is closed early if the view is closed during the scan.
+func (v *View[K, V]) Keys(ctx context.Context) <-chan K {
+	ch := make(chan K)
+	go func(ch chan<- K) {
+		defer close(ch)
+		var from *K
+		for {
+			keys := v.scan(from, viewScanSize)
+			for _, k := range keys {
+				select {
+				case <-ctx.Done():
+					return
+				case ch <- k:
+				}
+			}
+			if len(keys) < viewScanSize {
+				return
+			}
+			from = &keys[len(keys)-1]
+		}
+	}(ch)
+	return ch
+}
+
+// Count returns the number of keys present at the views version.
+func (v *View[K, V]) Count() int {
+	count := 0
+	for range v.Keys(context.Background()) {
+		count++
+	}
+	return count
+}
+
+// Close releases the view, allowing the versions it sees to be pruned.
+// Reads from the view, once closed, find no keys.
+func (v *View[K, V]) Close() {
+	v.tree.closeView(v)
+}
+
+// scan returns up to size keys, visible to the view, following the given key, or none once the view is closed.
+func (v *View[K, V]) scan(from *K, size int) []K {
+	v.tree.mu.RLock()
+	defer v.tree.mu.RUnlock()
+	if v.closed {
+		return nil
+	}
+	var keys []K
+	v.tree.tree.rootnode.ascend(from, false, func(e *nodeEntry[K, *versionChain[V]]) bool {
+		if e.Value.at(v.version) != nil {
+			keys = append(keys, e.Key)
+		}
+		return len(keys) < size
+	})
+	return keys
+}
+
+// NewVersionedTree creates a new, empty, VersionedTree of the given degree, at version zero.
+func NewVersionedTree[K cmp.Ordered, V any](degree int) *VersionedTree[K, V] {
+	return &VersionedTree[K, V]{
+		tree:  newBTree[K, versionChain[V]](degree),
+		views: map[*View[K, V]]struct{}{},
+	}
+}
@@ -0,0 +1,176 @@
+package btree
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestVersionedTree_View(t *testing.T) {
+	vt := NewVersionedTree[int, string](3)
+	for i := 0; i < 10; i++ {
+		v := "-" + strconv.Itoa(i) + "-"
+		if _, err := vt.Add(i, &v); err != nil {
+			t.Error(err)
+		}
+	}
+	v10 := vt.Current()
+	defer v10.Close()
+	if v10.Version() != 10 {
+		t.Errorf("expected version %d, found %d", 10, v10.Version())
+	}
+
+	updated := "updated"
+	if _, err := vt.Add(3, &updated); err != nil {
+		t.Error(err)
+	}
+	if _, err := vt.Remove(4); err != nil {
+		t.Error(err)
+	}
+	if _, err := vt.Remove(4); err == nil {
+		t.Error("Expected error removing key already removed, got nil")
+	}
+
+	if v := v10.Get(3); v == nil || *v != "-3-" {
+		t.Errorf("expected view to see original value of key %d, got %v", 3, v)
+	}
+	if v10.Get(4) == nil {
+		t.Errorf("expected view to see removed key %d", 4)
+	}
+	if v10.Count() != 10 {
+		t.Errorf("expected %d keys in view, found %d", 10, v10.Count())
+	}
+	if v := vt.Get(3); v == nil || *v != updated {
+		t.Errorf("expected current value %s of key %d, got %v", updated, 3, v)
+	}
+	if vt.Get(4) != nil {
+		t.Errorf("expected key %d removed from current version", 4)
+	}
+
+	v5, err := vt.View(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []int
+	for k := range v5.Keys(context.Background()) {
+		keys = append(keys, k)
+	}
+	if len(keys) != 5 || keys[4] != 4 {
+		t.Errorf("expected keys 0 to 4 in version 5, found %v", keys)
+	}
+	v5.Close()
+	if _, err := vt.View(100); err == nil {
+		t.Error("Expected error opening future version, got nil")
+	}
+}
+
+func TestVersionedTree_CollectGarbage(t *testing.T) {
+	vt := NewVersionedTree[int, string](3)
+	for round := 0; round < 3; round++ {
+		for i := 0; i < 10; i++ {
+			v := strconv.Itoa(round)
+			if _, err := vt.Add(i, &v); err != nil {
+				t.Error(err)
+			}
+		}
+	}
+	view, err := vt.View(15)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := vt.Remove(i); err != nil {
+			t.Error(err)
+		}
+	}
+	// view at 15 sees round 1 for keys 0-4 and round 0 for keys 5-9, only round 0 of keys 0-4 can go
+	pruned := vt.CollectGarbage()
+	if pruned != 5 {
+		t.Errorf("expected %d versions pruned, found %d", 5, pruned)
+	}
+	for i := 0; i < 10; i++ {
+		expect := "1"
+		if i >= 5 {
+			expect = "0"
+		}
+		if v := view.Get(i); v == nil || *v != expect {
+			t.Errorf("expected value %s of key %d in view after garbage collection, got %v", expect, i, v)
+		}
+	}
+	if _, err := vt.View(10); err == nil {
+		t.Error("Expected error opening pruned version, got nil")
+	}
+
+	view.Close()
+	pruned = vt.CollectGarbage()
+	// keys 0-4 are gone entirely, with their remaining 3 versions. keys 5-9 keep only their latest of 3.
+	if pruned != 5*3+5*2 {
+		t.Errorf("expected %d versions pruned, found %d", 5*3+5*2, pruned)
+	}
+	if vt.tree.Count() != 5 {
+		t.Errorf("expected %d keys left in tree, found %d", 5, vt.tree.Count())
+	}
+}
+
+func TestVersionedTree_ClosedView(t *testing.T) {
+	vt := NewVersionedTree[int, string](3)
+	for i := 0; i < 10; i++ {
+		v := strconv.Itoa(i)
+		if _, err := vt.Add(i, &v); err != nil {
+			t.Error(err)
+		}
+	}
+	view, err := vt.View(vt.Version())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if view.Count() != 10 {
+		t.Errorf("expected %d keys in view, found %d", 10, view.Count())
+	}
+	view.Close()
+	if v := view.Get(5); v != nil {
+		t.Errorf("expected nil reading key %d from closed view, got %v", 5, *v)
+	}
+	if view.Count() != 0 {
+		t.Errorf("expected no keys in closed view, found %d", view.Count())
+	}
+}
+
+func TestVersionedTree_ConcurrentScan(t *testing.T) {
+	vt := NewVersionedTree[int, string](5)
+	count := 2000
+	for i := 0; i < count; i++ {
+		v := "a"
+		if _, err := vt.Add(i, &v); err != nil {
+			t.Error(err)
+		}
+	}
+	view := vt.Current()
+	defer view.Close()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < count; i += 2 {
+			if _, err := vt.Remove(i); err != nil {
+				t.Error(err)
+			}
+			v := "b"
+			if _, err := vt.Add(i+count, &v); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	found := 0
+	for k := range view.Keys(context.Background()) {
+		if v := view.Get(k); v == nil || *v != "a" {
+			t.Errorf("view saw a later value for key %d", k)
+		}
+		found++
+	}
+	wg.Wait()
+	if found != count {
+		t.Errorf("expected view to see %d keys, found %d", count, found)
+	}
+}
@@ -0,0 +1,931 @@
+package btree
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SyncPolicy determines when the write-ahead log is flushed to stable storage.
+type SyncPolicy int
+
+const (
+	// SyncEveryOp syncs the log after every write, before the write is applied to the tree.
+	SyncEveryOp SyncPolicy = iota
+	// SyncBatch syncs the log once every WALOptions.BatchSize writes.
+	SyncBatch
+	// SyncInterval syncs the log in the background, once every WALOptions.Interval.
+	SyncInterval
+)
+
+// WALOptions configures a write-ahead log.
+type WALOptions struct {
+	Sync      SyncPolicy
+	BatchSize int
+	Interval  time.Duration
+	// Checkpoints, when set, enables automatic checkpoints.
+	Checkpoints *CheckpointOptions
+}
+
+// CheckpointOptions configures the automatic checkpoints of a WALTree.
+// A checkpoint is taken every Interval, or after every Ops writes, whichever comes first.
+type CheckpointOptions struct {
+	// Dir is the directory the snapshots are written to.
+	// When the log is opened, the latest snapshot in Dir is loaded before the log is replayed.
+	Dir string
+	// Interval is the time between checkpoints. Zero disables timed checkpoints.
+	Interval time.Duration
+	// Ops is the number of writes which trigger a checkpoint. Zero disables counted checkpoints.
+	Ops int
+	// Retain is the number of snapshots to keep, older snapshots are removed. Zero keeps all snapshots.
+	Retain int
+	// OnError, when set, is called with any error from a background checkpoint.
+	OnError func(error)
+}
+
+// WALTree is a BTree which records every write in a write-ahead log before applying it.
+type WALTree[K cmp.Ordered, V any] interface {
+	BTree[K, V]
+	// Sync flushes any unsynced log records to stable storage.
+	Sync() error
+	// Truncate discards all the records in the log.
+	// It should only be called once the state of the tree has been persisted elsewhere.
+	Truncate() error
+	// Checkpoint writes a snapshot of the tree to the given path and discards the log records it covers.
+	// When automatic checkpoints are enabled, the snapshot is also written to their Dir, to be loaded when the log is opened.
+	// Writes may continue while the snapshot is being written. A tree made by NewBTree is copied a chunk of keys at a time,
+	// so writes only wait for each chunk, other trees are copied whole, with writes waiting until the copy is made.
+	Checkpoint(path string) error
+	// Close syncs and closes the log. The tree remains readable, but may no longer be written to.
+	Close() error
+}
+
+const (
+	walAdd byte = iota + 1
+	walRemove
+	// walTxnBegin and walTxnEnd enclose the writes of a committed transaction,
+	// which are only replayed once the end has been logged.
+	walTxnBegin
+	walTxnEnd
+	// walRemoveRange removes the keys from Key to Hi, inclusive.
+	walRemoveRange
+	// walSplit moves every key out of the tree, by splitting it at Key.
+	walSplit
+)
+
+// walHeaderSize is the size of the length and checksum preceding each log record.
+const walHeaderSize = 8
+
+// maxWALRecordSize guards against reading a corrupt length as an enormous record.
+const maxWALRecordSize = 1 << 28
+
+var errWALClosed = errors.New("write-ahead log is closed")
+
+// errTornRecord indicates a record that was only partly written, or is corrupt.
+var errTornRecord = errors.New("torn write-ahead log record")
+
+// walRecord is a single write operation, as stored in the log.
+// HasValue distinguishes a nil value from a pointer to a zero value, which gob does not preserve.
+type walRecord[K cmp.Ordered, V any] struct {
+	Op       byte
+	Key      K
+	Value    *V
+	HasValue bool
+	Hi       K
+}
+
+// walWriter writes to a walTree whose write lock is already held.
+type walWriter[K cmp.Ordered, V any] struct {
+	w *walTree[K, V]
+}
+
+func (ww walWriter[K, V]) Get(key K) *V {
+	return ww.w.tree.Get(key)
+}
+
+func (ww walWriter[K, V]) Add(key K, value *V) error {
+	return ww.w.add(key, value)
+}
+
+func (ww walWriter[K, V]) Remove(key K) error {
+	return ww.w.remove(key)
+}
+
+func (ww walWriter[K, V]) contains(key K) bool {
+	return containsKey(ww.w.tree, key)
+}
+
+// undoWriter writes the undoing of a failed commit straight to the tree, without logging it.
+// The log is cut back to before the transaction, so its records need no undoing,
+// and a log which failed the commit would fail the undo in the same way.
+func (ww walWriter[K, V]) undoWriter() treeWriter[K, V] {
+	return ww.w.tree
+}
+
+// snapshotPattern names the snapshots written by automatic checkpoints, in sequence.
+const snapshotPattern = "snapshot-%020d.snap"
+
+type walTree[K cmp.Ordered, V any] struct {
+	tree    BTree[K, V]
+	path    string
+	file    *os.File
+	opts    WALOptions
+	mu      sync.RWMutex
+	pending int
+	done    chan struct{}
+	wg      sync.WaitGroup
+	// failed is the error which left the log unusable. Once set, every append, Sync and Close returns it.
+	failed error
+
+	// ckptMu serialises checkpoints, ops counts the writes since the last one.
+	ckptMu     sync.Mutex
+	ops        int
+	opsReached chan struct{}
+	snapSeq    int
+}
+
+func (w *walTree[K, V]) Degree() int {
+	return w.tree.Degree()
+}
+
+func (w *walTree[K, V]) Depth() int {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.tree.Depth()
+}
+
+func (w *walTree[K, V]) IsEmpty() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.tree.IsEmpty()
+}
+
+// Keys returns the keys present when it is called.
+// The keys are collected before returning, so the tree may be written to while the keys are read.
+func (w *walTree[K, V]) Keys(ctx context.Context) <-chan K {
+	w.mu.RLock()
+	var keys []K
+	for k := range w.tree.Keys(ctx) {
+		keys = append(keys, k)
+	}
+	w.mu.RUnlock()
+
+	ch := make(chan K)
+	go func(ch chan<- K) {
+		defer close(ch)
+		for _, k := range keys {
+			select {
+			case <-ctx.Done():
+				return
+			case ch <- k:
+			}
+		}
+	}(ch)
+	return ch
+}
+
+func (w *walTree[K, V]) Get(key K) *V {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.tree.Get(key)
+}
+
+func (w *walTree[K, V]) Count() int {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.tree.Count()
+}
+
+func (w *walTree[K, V]) Add(key K, value *V) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.add(key, value)
+}
+
+func (w *walTree[K, V]) Remove(key K) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.remove(key)
+}
+
+func (w *walTree[K, V]) Begin() Txn[K, V] {
+	return newTxn[K, V](w, func(commit func(treeWriter[K, V]) error) error {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if w.file == nil {
+			return errWALClosed
+		}
+		offset, err := w.file.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+		if err := w.append(walRecord[K, V]{Op: walTxnBegin}); err != nil {
+			return err
+		}
+		if err := commit(walWriter[K, V]{w}); err != nil {
+			// a failed commit has undone its writes, so the log is cut back to before the transaction.
+			w.rewind(offset, err)
+			return err
+		}
+		if err := w.append(walRecord[K, V]{Op: walTxnEnd}); err != nil {
+			// the writes are in the tree, but without the end they would never be replayed,
+			// and would hide every record logged after them.
+			w.rewind(offset, err)
+			w.fail(err)
+			return err
+		}
+		return nil
+	})
+}
+
+// Apply logs every write in the batch before applying those logged to the tree.
+// A write which fails to be logged is not applied, and has the logging error as its result.
+func (w *walTree[K, V]) Apply(batch *Batch[K, V]) []error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	results := make([]error, batch.Len())
+	logged := &Batch[K, V]{}
+	for _, op := range sortBatchOps(batch.ops) {
+		rec := walRecord[K, V]{Op: walAdd, Key: op.key, Value: op.value, HasValue: op.value != nil}
+		if op.remove {
+			rec = walRecord[K, V]{Op: walRemove, Key: op.key}
+		}
+		if err := w.append(rec); err != nil {
+			results[op.index] = err
+			continue
+		}
+		logged.ops = append(logged.ops, batchOp[K, V]{key: op.key, value: op.value, remove: op.remove, index: len(logged.ops)})
+	}
+	for i, err := range w.tree.Apply(logged) {
+		results[logged.ops[i].index] = err
+	}
+	return results
+}
+
+func (w *walTree[K, V]) Watch(lo, hi K, opts WatchOptions) *Watcher[K, V] {
+	return w.tree.Watch(lo, hi, opts)
+}
+
+// holdEvents holds back the events of the tree, when it can.
+func (w *walTree[K, V]) holdEvents() {
+	if h, ok := w.tree.(eventHolder); ok {
+		h.holdEvents()
+	}
+}
+
+func (w *walTree[K, V]) releaseEvents(send bool) {
+	if h, ok := w.tree.(eventHolder); ok {
+		h.releaseEvents(send)
+	}
+}
+
+// Update logs the change made by fn before it is applied to the tree.
+func (w *walTree[K, V]) Update(key K, fn UpdateFunc[V]) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var logErr error
+	err := w.tree.Update(key, func(old *V, exists bool) (*V, UpdateAction) {
+		value, action := fn(old, exists)
+		switch {
+		case action == UpdateSet:
+			logErr = w.append(walRecord[K, V]{Op: walAdd, Key: key, Value: value, HasValue: value != nil})
+		case action == UpdateRemove && exists:
+			logErr = w.append(walRecord[K, V]{Op: walRemove, Key: key})
+		}
+		if logErr != nil {
+			return old, UpdateKeep
+		}
+		return value, action
+	})
+	if logErr != nil {
+		return logErr
+	}
+	return err
+}
+
+func (w *walTree[K, V]) CompareAndSwap(key K, old, new *V, eq func(a, b *V) bool) (bool, error) {
+	return compareAndSwap(w.Update, key, old, new, eq)
+}
+
+func (w *walTree[K, V]) CompareAndDelete(key K, old *V, eq func(a, b *V) bool) (bool, error) {
+	return compareAndDelete(w.Update, key, old, eq)
+}
+
+func (w *walTree[K, V]) GetOrCompute(key K, build func() (*V, error)) (*V, error) {
+	return getOrCompute(w.Update, key, build)
+}
+
+// DeleteRange logs the range before removing it from the tree.
+// If the range can not be logged, nothing is removed, and zero is returned.
+// The log is then failed, so the error is returned by the next write, Sync or Close.
+func (w *walTree[K, V]) DeleteRange(lo, hi K) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.append(walRecord[K, V]{Op: walRemoveRange, Key: lo, Hi: hi}); err != nil {
+		w.fail(err)
+		return 0
+	}
+	return w.tree.DeleteRange(lo, hi)
+}
+
+// SplitAt logs the split, leaving the logged tree empty, and returns the two halves as trees without a log.
+// If the split can not be logged, the tree is left unchanged, and the logging error is returned.
+func (w *walTree[K, V]) SplitAt(key K) (left, right BTree[K, V], err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.append(walRecord[K, V]{Op: walSplit, Key: key}); err != nil {
+		return nil, nil, err
+	}
+	return w.tree.SplitAt(key)
+}
+
+// MemoryUsage returns the estimated bytes held by the tree, not including its log.
+func (w *walTree[K, V]) MemoryUsage() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.tree.MemoryUsage()
+}
+
+func (w *walTree[K, V]) Stats() TreeStats {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.tree.Stats()
+}
+
+func (w *walTree[K, V]) Validate() error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.tree.Validate()
+}
+
+func (w *walTree[K, V]) contains(key K) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return containsKey(w.tree, key)
+}
+
+// add logs and applies the given key and value.
+// Caller must hold the write lock.
+func (w *walTree[K, V]) add(key K, value *V) error {
+	if err := w.append(walRecord[K, V]{Op: walAdd, Key: key, Value: value, HasValue: value != nil}); err != nil {
+		return err
+	}
+	return w.tree.Add(key, value)
+}
+
+// remove logs and applies the removal of the given key.
+// Caller must hold the write lock.
+func (w *walTree[K, V]) remove(key K) error {
+	if err := w.append(walRecord[K, V]{Op: walRemove, Key: key}); err != nil {
+		return err
+	}
+	// A logged remove of an unknown key is harmless, it is ignored on replay.
+	return w.tree.Remove(key)
+}
+
+func (w *walTree[K, V]) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.sync()
+}
+
+func (w *walTree[K, V]) Truncate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return errWALClosed
+	}
+	if err := w.file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := w.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	w.pending = 0
+	return w.file.Sync()
+}
+
+func (w *walTree[K, V]) Close() error {
+	if w.done != nil {
+		close(w.done)
+		w.wg.Wait()
+		w.done = nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return errWALClosed
+	}
+	err := w.sync()
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+	w.file = nil
+	return err
+}
+
+// fail marks the log as failed with the given error, refusing any further appends.
+// Caller must hold the write lock.
+func (w *walTree[K, V]) fail(err error) {
+	if w.failed == nil {
+		w.failed = fmt.Errorf("write-ahead log failed: %w", err)
+	}
+}
+
+// rewind removes anything written to the log after the given offset, by a failed append.
+// If the log can not be rewound, it is marked as failed, as records appended after the partial write would be lost on replay.
+// Caller must hold the write lock.
+func (w *walTree[K, V]) rewind(offset int64, cause error) {
+	if err := w.file.Truncate(offset); err != nil {
+		w.fail(cause)
+		return
+	}
+	if _, err := w.file.Seek(offset, io.SeekStart); err != nil {
+		w.fail(cause)
+	}
+}
+
+// append writes the given record to the end of the log, syncing it according to the sync policy.
+// Caller must hold the write lock.
+func (w *walTree[K, V]) append(rec walRecord[K, V]) error {
+	if w.file == nil {
+		return errWALClosed
+	}
+	if w.failed != nil {
+		return w.failed
+	}
+	buf, err := encodeWALRecord(rec)
+	if err != nil {
+		return err
+	}
+	offset, err := w.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := w.file.Write(buf); err != nil {
+		// a short write would leave a torn record, ending the log on replay, ahead of any later records.
+		w.rewind(offset, err)
+		return err
+	}
+	w.pending++
+	w.ops++
+	if w.opsReached != nil && w.ops >= w.opts.Checkpoints.Ops {
+		select {
+		case w.opsReached <- struct{}{}:
+		default:
+		}
+	}
+	switch w.opts.Sync {
+	case SyncEveryOp:
+		err = w.sync()
+	case SyncBatch:
+		if w.pending >= w.opts.BatchSize {
+			err = w.sync()
+		}
+	}
+	if err != nil {
+		// the write is not applied, so its record must not be replayed, once a later sync succeeds.
+		w.rewind(offset, err)
+	}
+	return err
+}
+
+// sync flushes the log if it has any unsynced records.
+// Caller must hold the write lock.
+func (w *walTree[K, V]) sync() error {
+	if w.file == nil {
+		return errWALClosed
+	}
+	if w.failed != nil {
+		return w.failed
+	}
+	if w.pending == 0 {
+		return nil
+	}
+	if err := w.file.Sync(); err != nil {
+		return err
+	}
+	w.pending = 0
+	return nil
+}
+
+func (w *walTree[K, V]) syncEvery(interval time.Duration) {
+	defer w.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			w.mu.Lock()
+			if w.file != nil {
+				// a failed sync leaves the records pending, to be retried on the next tick or Close.
+				_ = w.sync()
+			}
+			w.mu.Unlock()
+		}
+	}
+}
+
+// Checkpoint writes a snapshot to the given path, then discards the log records it covers.
+// When automatic checkpoints are enabled, the snapshot is also written as the next in the sequence in their Dir,
+// which is the only snapshot read when the log is opened.
+func (w *walTree[K, V]) Checkpoint(path string) error {
+	w.ckptMu.Lock()
+	defer w.ckptMu.Unlock()
+	if w.opts.Checkpoints != nil {
+		return w.checkpointNext(path)
+	}
+	return w.checkpoint(path)
+}
+
+// checkpoint writes a snapshot of the tree to each of the given paths, then discards the log records it covers.
+// Caller must hold ckptMu.
+func (w *walTree[K, V]) checkpoint(paths ...string) error {
+	// note how much of the log the copy will cover, then let writes continue while the copy is saved.
+	w.mu.Lock()
+	if w.file == nil {
+		w.mu.Unlock()
+		return errWALClosed
+	}
+	offset, err := w.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		w.mu.Unlock()
+		return err
+	}
+	w.ops = 0
+	bt, chunked := w.tree.(*bTree[K, V])
+	var snap BTree[K, V]
+	if !chunked {
+		// other trees are copied whole, so writes wait until every key is copied
+		snap = copyTree(w.tree)
+	}
+	w.mu.Unlock()
+	if chunked {
+		snap = w.copyChunks(bt)
+	}
+
+	for _, path := range paths {
+		if err := SaveSnapshot(path, snap); err != nil {
+			return err
+		}
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.discard(offset)
+}
+
+// walCopyChunk is the number of keys a checkpoint copies under each read lock.
+const walCopyChunk = 1024
+
+// copyChunks copies the given tree, walCopyChunk keys at a time, each under the read lock, so writes may continue between them.
+// The copy is of no one moment, but every write made after the checkpoint offset is in the log after it,
+// and replaying those records over the copy sets, or removes, each key they wrote, bringing it up to date.
+func (w *walTree[K, V]) copyChunks(bt *bTree[K, V]) BTree[K, V] {
+	nt := newBTree[K, V](bt.degree)
+	var from *K
+	for {
+		n := 0
+		w.mu.RLock()
+		bt.rootnode.ascend(from, false, func(e *nodeEntry[K, *V]) bool {
+			_ = nt.Add(e.Key, e.Value)
+			k := e.Key
+			from = &k
+			n++
+			return n < walCopyChunk
+		})
+		w.mu.RUnlock()
+		if n < walCopyChunk {
+			return nt
+		}
+	}
+}
+
+// discard removes the first offset bytes of the log, once they are covered by a checkpoint.
+// The remaining records are copied to a new log which replaces the current one.
+// Caller must hold the write lock.
+func (w *walTree[K, V]) discard(offset int64) error {
+	if w.file == nil {
+		return errWALClosed
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(w.path), filepath.Base(w.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	err = copyFrom(tmp, w.file, offset)
+	if err == nil {
+		err = tmp.Chmod(0o644)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), w.path)
+	}
+	if err != nil {
+		tmp.Close()
+		// leave the current log as it was, positioned for the next append.
+		_, _ = w.file.Seek(0, io.SeekEnd)
+		return err
+	}
+	syncDir(filepath.Dir(w.path))
+	w.file.Close()
+	w.file = tmp
+	w.pending = 0
+	return nil
+}
+
+// checkpointEvery takes a checkpoint on each interval or when the ops count is reached, until the log is closed.
+func (w *walTree[K, V]) checkpointEvery(opts CheckpointOptions) {
+	defer w.wg.Done()
+	var tick <-chan time.Time
+	if opts.Interval > 0 {
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-tick:
+		case <-w.opsReached:
+		}
+		if err := w.checkpointChanged(); err != nil && opts.OnError != nil {
+			opts.OnError(err)
+		}
+	}
+}
+
+// checkpointChanged takes the next checkpoint, unless nothing has been written since the last.
+func (w *walTree[K, V]) checkpointChanged() error {
+	w.ckptMu.Lock()
+	defer w.ckptMu.Unlock()
+	w.mu.RLock()
+	ops := w.ops
+	w.mu.RUnlock()
+	if ops == 0 {
+		return nil
+	}
+	return w.checkpointNext()
+}
+
+// checkpointNext writes the next snapshot in the sequence, and to any other given paths,
+// removing any older than the retained count.
+// Caller must hold ckptMu.
+func (w *walTree[K, V]) checkpointNext(paths ...string) error {
+	opts := w.opts.Checkpoints
+	next := filepath.Join(opts.Dir, fmt.Sprintf(snapshotPattern, w.snapSeq+1))
+	if err := w.checkpoint(append([]string{next}, paths...)...); err != nil {
+		return err
+	}
+	w.snapSeq++
+	if opts.Retain < 1 {
+		return nil
+	}
+	snaps, err := listSnapshots(opts.Dir)
+	if err != nil {
+		return err
+	}
+	for len(snaps) > opts.Retain {
+		if err := os.Remove(snaps[0].path); err != nil {
+			return err
+		}
+		snaps = snaps[1:]
+	}
+	return nil
+}
+
+// replay applies every complete record in the log to the tree.
+// The log is truncated after the last complete record, discarding any torn write left by a crash,
+// along with any transaction whose end was not logged.
+func (w *walTree[K, V]) replay() error {
+	r := bufio.NewReader(w.file)
+	var offset, txnOffset int64
+	var txn []walRecord[K, V]
+	inTxn := false
+	for {
+		rec, n, err := readWALRecord[K, V](r)
+		if errors.Is(err, io.EOF) || errors.Is(err, errTornRecord) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		switch {
+		case rec.Op == walTxnBegin:
+			// any transaction still open was never committed, so its records are dropped.
+			inTxn, txnOffset, txn = true, offset, nil
+		case rec.Op == walTxnEnd:
+			for _, rec := range txn {
+				if err := w.apply(rec); err != nil {
+					return err
+				}
+			}
+			inTxn, txn = false, nil
+		case inTxn:
+			txn = append(txn, rec)
+		default:
+			if err := w.apply(rec); err != nil {
+				return err
+			}
+		}
+		offset += n
+	}
+	if inTxn {
+		offset = txnOffset
+	}
+	if err := w.file.Truncate(offset); err != nil {
+		return err
+	}
+	_, err := w.file.Seek(offset, io.SeekStart)
+	return err
+}
+
+func (w *walTree[K, V]) apply(rec walRecord[K, V]) error {
+	switch rec.Op {
+	case walAdd:
+		if rec.HasValue && rec.Value == nil {
+			rec.Value = new(V)
+		}
+		return w.tree.Add(rec.Key, rec.Value)
+	case walRemove:
+		// the key may never have existed when the remove was logged.
+		_ = w.tree.Remove(rec.Key)
+		return nil
+	case walRemoveRange:
+		w.tree.DeleteRange(rec.Key, rec.Hi)
+		return nil
+	case walSplit:
+		_, _, err := w.tree.SplitAt(rec.Key)
+		return err
+	default:
+		return fmt.Errorf("unknown write-ahead log operation %d", rec.Op)
+	}
+}
+
+func encodeWALRecord[K cmp.Ordered, V any](rec walRecord[K, V]) ([]byte, error) {
+	buf := bytes.NewBuffer(make([]byte, walHeaderSize))
+	if err := gob.NewEncoder(buf).Encode(rec); err != nil {
+		return nil, err
+	}
+	b := buf.Bytes()
+	payload := b[walHeaderSize:]
+	binary.LittleEndian.PutUint32(b[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(b[4:8], crc32.ChecksumIEEE(payload))
+	return b, nil
+}
+
+// readWALRecord reads the next record from the given reader, returning it with its size in bytes.
+// io.EOF is returned at the clean end of the log, errTornRecord for an incomplete or corrupt record.
+func readWALRecord[K cmp.Ordered, V any](r io.Reader) (walRecord[K, V], int64, error) {
+	var rec walRecord[K, V]
+	var header [walHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return rec, 0, errTornRecord
+		}
+		return rec, 0, err
+	}
+	size := binary.LittleEndian.Uint32(header[0:4])
+	if size > maxWALRecordSize {
+		return rec, 0, errTornRecord
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return rec, 0, errTornRecord
+		}
+		return rec, 0, err
+	}
+	if crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(header[4:8]) {
+		return rec, 0, errTornRecord
+	}
+	if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&rec); err != nil {
+		return rec, 0, errTornRecord
+	}
+	return rec, int64(walHeaderSize) + int64(size), nil
+}
+
+// OpenWAL opens, or creates, the write-ahead log at the given path and replays it into the given tree.
+// The returned tree logs every Add and Remove before applying it to the given tree,
+// which should not be written to directly once the log is open.
+func OpenWAL[K cmp.Ordered, V any](path string, tree BTree[K, V], opts WALOptions) (WALTree[K, V], error) {
+	switch opts.Sync {
+	case SyncEveryOp:
+	case SyncBatch:
+		if opts.BatchSize < 1 {
+			return nil, fmt.Errorf("batch size must be >= 1")
+		}
+	case SyncInterval:
+		if opts.Interval <= 0 {
+			return nil, fmt.Errorf("sync interval must be > 0")
+		}
+	default:
+		return nil, fmt.Errorf("unknown sync policy %d", opts.Sync)
+	}
+	if ckpt := opts.Checkpoints; ckpt != nil {
+		if ckpt.Dir == "" {
+			return nil, fmt.Errorf("checkpoint directory must be set")
+		}
+		if err := os.MkdirAll(ckpt.Dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	w := &walTree[K, V]{
+		tree: tree,
+		path: path,
+		file: f,
+		opts: opts,
+	}
+	if opts.Checkpoints != nil {
+		if err := w.loadLatestSnapshot(opts.Checkpoints.Dir); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	if err := w.replay(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if opts.Sync == SyncInterval {
+		w.done = make(chan struct{})
+		w.wg.Add(1)
+		go w.syncEvery(opts.Interval)
+	}
+	if ckpt := opts.Checkpoints; ckpt != nil && (ckpt.Interval > 0 || ckpt.Ops > 0) {
+		if w.done == nil {
+			w.done = make(chan struct{})
+		}
+		if ckpt.Ops > 0 {
+			w.opsReached = make(chan struct{}, 1)
+		}
+		w.wg.Add(1)
+		go w.checkpointEvery(*ckpt)
+	}
+	return w, nil
+}
+
+// loadLatestSnapshot loads the most recent snapshot in the given directory into the tree, if there is one.
+func (w *walTree[K, V]) loadLatestSnapshot(dir string) error {
+	snaps, err := listSnapshots(dir)
+	if err != nil {
+		return err
+	}
+	if len(snaps) == 0 {
+		return nil
+	}
+	latest := snaps[len(snaps)-1]
+	w.snapSeq = latest.seq
+	return LoadSnapshot(latest.path, w.tree)
+}
+
+type snapshotFile struct {
+	path string
+	seq  int
+}
+
+// listSnapshots returns the snapshots written by automatic checkpoints in the given directory, oldest first.
+func listSnapshots(dir string) ([]snapshotFile, error) {
+	des, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var snaps []snapshotFile
+	for _, de := range des {
+		var seq int
+		if _, err := fmt.Sscanf(de.Name(), snapshotPattern, &seq); err != nil || de.IsDir() {
+			continue
+		}
+		if de.Name() != fmt.Sprintf(snapshotPattern, seq) {
+			// a temporary file, or some other file with a similar name
+			continue
+		}
+		snaps = append(snaps, snapshotFile{path: filepath.Join(dir, de.Name()), seq: seq})
+	}
+	sort.Slice(snaps, func(i, j int) bool {
+		return snaps[i].seq < snaps[j].seq
+	})
+	return snaps, nil
+}
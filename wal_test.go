@@ -0,0 +1,641 @@
+package btree
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestWAL_Replay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tree.wal")
+	wt, err := OpenWAL[int, string](path, NewBTree[int, string](3), WALOptions{Sync: SyncEveryOp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 20
+	if err := fillTree(wt, count); err != nil {
+		t.Error(err)
+	}
+	for i := 0; i < count; i += 3 {
+		if err := wt.Remove(i); err != nil {
+			t.Errorf("unexpected error removing key %d.  %v", i, err)
+		}
+	}
+	empty := ""
+	if err := wt.Add(1, &empty); err != nil {
+		t.Error(err)
+	}
+	if err := wt.Add(2, nil); err != nil {
+		t.Error(err)
+	}
+	if err := wt.Close(); err != nil {
+		t.Error(err)
+	}
+	if err := wt.Add(99, nil); err == nil {
+		t.Error("Expected error adding to closed log, got nil")
+	}
+
+	bt := NewBTree[int, string](3)
+	wt, err = OpenWAL[int, string](path, bt, WALOptions{Sync: SyncEveryOp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.Close()
+	if err := validateTree(bt); err != nil {
+		t.Error(err)
+	}
+	expect := count - (count+2)/3
+	if bt.Count() != expect {
+		t.Errorf("expected %d entries after replay, found %d", expect, bt.Count())
+	}
+	if v := bt.Get(1); v == nil || *v != "" {
+		t.Errorf("expected empty string value for key %d after replay, got %v", 1, v)
+	}
+	if v := bt.Get(2); v != nil {
+		t.Errorf("expected nil value for key %d after replay, got %v", 2, *v)
+	}
+	if v := bt.Get(4); v == nil || *v != "-4-" {
+		t.Errorf("expected value %s for key %d after replay, got %v", "-4-", 4, v)
+	}
+	if v := bt.Get(3); v != nil {
+		t.Errorf("expected key %d to be removed after replay, found %v", 3, *v)
+	}
+}
+
+func TestWAL_Truncate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tree.wal")
+	wt, err := OpenWAL[int, string](path, NewBTree[int, string](3), WALOptions{Sync: SyncBatch, BatchSize: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fillTree(wt, 10); err != nil {
+		t.Error(err)
+	}
+	if err := wt.Truncate(); err != nil {
+		t.Error(err)
+	}
+	if err := wt.Add(42, nil); err != nil {
+		t.Error(err)
+	}
+	if err := wt.Close(); err != nil {
+		t.Error(err)
+	}
+
+	bt := NewBTree[int, string](3)
+	wt, err = OpenWAL[int, string](path, bt, WALOptions{Sync: SyncEveryOp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.Close()
+	if bt.Count() != 1 {
+		t.Errorf("expected %d entry after truncated replay, found %d", 1, bt.Count())
+	}
+}
+
+func TestWAL_SyncInterval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tree.wal")
+	if _, err := OpenWAL[int, string](path, NewBTree[int, string](3), WALOptions{Sync: SyncInterval}); err == nil {
+		t.Error("Expected error opening log with zero interval, got nil")
+	}
+	wt, err := OpenWAL[int, string](path, NewBTree[int, string](3), WALOptions{Sync: SyncInterval, Interval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fillTree(wt, 50); err != nil {
+		t.Error(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if err := wt.Close(); err != nil {
+		t.Error(err)
+	}
+	bt := NewBTree[int, string](3)
+	wt, err = OpenWAL[int, string](path, bt, WALOptions{Sync: SyncEveryOp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.Close()
+	if err := checkContains(bt, 50); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestWAL_CrashRecovery simulates a crash part way through a write by cutting the log at random byte offsets.
+// The replayed tree must contain exactly the operations whose records were completely written.
+func TestWAL_CrashRecovery(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tree.wal")
+	wt, err := OpenWAL[int, string](path, NewBTree[int, string](3), WALOptions{Sync: SyncEveryOp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rnd := rand.New(rand.NewSource(1))
+	type op struct {
+		remove bool
+		key    int
+		end    int64
+	}
+	var ops []op
+	for i := 0; i < 200; i++ {
+		o := op{key: rnd.Intn(50), remove: rnd.Intn(3) == 0}
+		if o.remove {
+			_ = wt.Remove(o.key)
+		} else {
+			v := "-" + strconv.Itoa(o.key) + "-"
+			_ = wt.Add(o.key, &v)
+		}
+		fi, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		o.end = fi.Size()
+		ops = append(ops, o)
+	}
+	if err := wt.Close(); err != nil {
+		t.Error(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 100; i++ {
+		cut := rnd.Int63n(int64(len(data)) + 1)
+		crashed := filepath.Join(dir, fmt.Sprintf("crash-%d.wal", i))
+		if err := os.WriteFile(crashed, data[:cut], 0o644); err != nil {
+			t.Fatal(err)
+		}
+		expect := map[int]bool{}
+		var lastEnd int64
+		for _, o := range ops {
+			if o.end > cut {
+				break
+			}
+			expect[o.key] = !o.remove
+			lastEnd = o.end
+		}
+
+		bt := NewBTree[int, string](3)
+		wt, err := OpenWAL[int, string](crashed, bt, WALOptions{Sync: SyncEveryOp})
+		if err != nil {
+			t.Fatalf("unexpected error replaying log cut at %d.  %v", cut, err)
+		}
+		if err := validateTree(bt); err != nil && !bt.IsEmpty() {
+			t.Error(err)
+		}
+		if err := compareToModel(bt, expect); err != nil {
+			t.Errorf("log cut at offset %d: %v", cut, err)
+		}
+		// the torn tail must be discarded, so new records follow the last complete one.
+		fi, err := os.Stat(crashed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Size() != lastEnd {
+			t.Errorf("expected log truncated to %d bytes, found %d", lastEnd, fi.Size())
+		}
+		v := "after"
+		if err := wt.Add(1000, &v); err != nil {
+			t.Error(err)
+		}
+		if err := wt.Close(); err != nil {
+			t.Error(err)
+		}
+		bt = NewBTree[int, string](3)
+		wt, err = OpenWAL[int, string](crashed, bt, WALOptions{Sync: SyncEveryOp})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect[1000] = true
+		if err := compareToModel(bt, expect); err != nil {
+			t.Errorf("log cut at offset %d, after recovery write: %v", cut, err)
+		}
+		wt.Close()
+	}
+}
+
+func TestWAL_CorruptRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tree.wal")
+	wt, err := OpenWAL[int, string](path, NewBTree[int, string](3), WALOptions{Sync: SyncEveryOp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fillTree(wt, 1); err != nil {
+		t.Error(err)
+	}
+	fi, _ := os.Stat(path)
+	first := fi.Size()
+	v := "-1-"
+	if err := wt.Add(1, &v); err != nil {
+		t.Error(err)
+	}
+	if err := wt.Close(); err != nil {
+		t.Error(err)
+	}
+	data, _ := os.ReadFile(path)
+	// flip a byte in the second record's payload
+	data[len(data)-1] ^= 0xff
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	bt := NewBTree[int, string](3)
+	wt, err = OpenWAL[int, string](path, bt, WALOptions{Sync: SyncEveryOp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.Close()
+	if bt.Count() != 1 || bt.Get(0) == nil {
+		t.Errorf("expected only key 0 to survive a corrupt second record, found %d entries", bt.Count())
+	}
+	fi, _ = os.Stat(path)
+	if fi.Size() != first {
+		t.Errorf("expected log truncated to %d bytes, found %d", first, fi.Size())
+	}
+}
+
+// compareToModel checks the tree holds exactly the keys marked true in the given model.
+func compareToModel(bt BTree[int, string], model map[int]bool) error {
+	count := 0
+	for k, present := range model {
+		if !present {
+			if v := bt.Get(k); v != nil {
+				return fmt.Errorf("unexpected key %d found in tree", k)
+			}
+			continue
+		}
+		count++
+		if v := bt.Get(k); v == nil {
+			return fmt.Errorf("expected key %d not found in tree", k)
+		}
+	}
+	found := 0
+	for range bt.Keys(context.Background()) {
+		found++
+	}
+	if found != count {
+		return fmt.Errorf("expected %d keys in tree, found %d", count, found)
+	}
+	return nil
+}
+
+func TestWAL_Checkpoint(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tree.wal")
+	snap := filepath.Join(dir, "tree.snap")
+	wt, err := OpenWAL[int, string](path, NewBTree[int, string](3), WALOptions{Sync: SyncEveryOp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fillTree(wt, 30); err != nil {
+		t.Error(err)
+	}
+	if err := wt.Checkpoint(snap); err != nil {
+		t.Fatal(err)
+	}
+	fi, _ := os.Stat(path)
+	if fi.Size() != 0 {
+		t.Errorf("expected empty log after checkpoint, found %d bytes", fi.Size())
+	}
+	if err := wt.Remove(5); err != nil {
+		t.Error(err)
+	}
+	if err := wt.Close(); err != nil {
+		t.Error(err)
+	}
+
+	bt := NewBTree[int, string](3)
+	if err := LoadSnapshot(snap, bt); err != nil {
+		t.Fatal(err)
+	}
+	wt, err = OpenWAL[int, string](path, bt, WALOptions{Sync: SyncEveryOp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.Close()
+	if bt.Count() != 29 {
+		t.Errorf("expected %d entries after snapshot and replay, found %d", 29, bt.Count())
+	}
+	if bt.Get(5) != nil {
+		t.Errorf("expected key %d removed by replay after snapshot", 5)
+	}
+}
+
+func TestWAL_CheckpointWhileWriting(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tree.wal")
+	snap := filepath.Join(dir, "tree.snap")
+	wt, err := OpenWAL[int, string](path, NewBTree[int, string](5), WALOptions{Sync: SyncBatch, BatchSize: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := "value"
+	model := map[int]bool{}
+	for i := 0; i < 5*walCopyChunk; i++ {
+		if err := wt.Add(i, &v); err != nil {
+			t.Fatal(err)
+		}
+		model[i] = true
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r := rand.New(rand.NewSource(1))
+		for i := 0; i < 5000; i++ {
+			k := r.Intn(6 * walCopyChunk)
+			if r.Intn(2) == 0 {
+				if err := wt.Add(k, &v); err != nil {
+					t.Error(err)
+				}
+				model[k] = true
+			} else {
+				_ = wt.Remove(k)
+				model[k] = false
+			}
+		}
+	}()
+	if err := wt.Checkpoint(snap); err != nil {
+		t.Error(err)
+	}
+	<-done
+	if err := wt.Close(); err != nil {
+		t.Error(err)
+	}
+
+	bt := NewBTree[int, string](5)
+	if err := LoadSnapshot(snap, bt); err != nil {
+		t.Fatal(err)
+	}
+	wt, err = OpenWAL[int, string](path, bt, WALOptions{Sync: SyncEveryOp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.Close()
+	if err := compareToModel(bt, model); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWAL_CheckpointWithCheckpointsDir(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tree.wal")
+	snap := filepath.Join(dir, "tree.snap")
+	ckpt := &CheckpointOptions{Dir: filepath.Join(dir, "snapshots")}
+	wt, err := OpenWAL[int, string](path, NewBTree[int, string](3), WALOptions{Sync: SyncEveryOp, Checkpoints: ckpt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fillTree(wt, 30); err != nil {
+		t.Error(err)
+	}
+	if err := wt.Checkpoint(snap); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.Remove(5); err != nil {
+		t.Error(err)
+	}
+	if err := wt.Close(); err != nil {
+		t.Error(err)
+	}
+	snaps, err := listSnapshots(ckpt.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snaps) != 1 {
+		t.Errorf("expected manual checkpoint written to checkpoint directory, found %d snapshots", len(snaps))
+	}
+	sbt := NewBTree[int, string](3)
+	if err := LoadSnapshot(snap, sbt); err != nil {
+		t.Fatal(err)
+	}
+	if sbt.Count() != 30 {
+		t.Errorf("expected %d entries in snapshot at given path, found %d", 30, sbt.Count())
+	}
+
+	// the log records covered by the checkpoint are gone, so they must be loaded from the checkpoint directory
+	bt := NewBTree[int, string](3)
+	wt, err = OpenWAL[int, string](path, bt, WALOptions{Sync: SyncEveryOp, Checkpoints: ckpt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.Close()
+	if bt.Count() != 29 {
+		t.Errorf("expected %d entries after snapshot and replay, found %d", 29, bt.Count())
+	}
+	if bt.Get(5) != nil {
+		t.Errorf("expected key %d removed by replay after snapshot", 5)
+	}
+}
+
+func TestWAL_AutomaticCheckpoints(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tree.wal")
+	ckpt := &CheckpointOptions{
+		Dir:    filepath.Join(dir, "snapshots"),
+		Ops:    10,
+		Retain: 2,
+		OnError: func(err error) {
+			t.Error(err)
+		},
+	}
+	wt, err := OpenWAL[int, string](path, NewBTree[int, string](3), WALOptions{Sync: SyncEveryOp, Checkpoints: ckpt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 50; i++ {
+		if err := fillTree(wt, i); err != nil {
+			t.Error(err)
+		}
+	}
+	// checkpoints are taken in the background, wait for them to catch up
+	var snaps []snapshotFile
+	for i := 0; i < 100 && len(snaps) < ckpt.Retain; i++ {
+		time.Sleep(10 * time.Millisecond)
+		if snaps, err = listSnapshots(ckpt.Dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := wt.Close(); err != nil {
+		t.Error(err)
+	}
+	if snaps, err = listSnapshots(ckpt.Dir); err != nil {
+		t.Fatal(err)
+	}
+	if len(snaps) != ckpt.Retain {
+		t.Errorf("expected %d retained snapshots, found %d", ckpt.Retain, len(snaps))
+	}
+
+	bt := NewBTree[int, string](3)
+	wt, err = OpenWAL[int, string](path, bt, WALOptions{Sync: SyncEveryOp, Checkpoints: &CheckpointOptions{Dir: ckpt.Dir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.Close()
+	if err := checkContains(bt, 49); err != nil {
+		t.Error(err)
+	}
+	if err := validateTree(bt); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWAL_Apply(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tree.wal")
+	wt, err := OpenWAL[int, string](path, NewBTree[int, string](3), WALOptions{Sync: SyncEveryOp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var batch Batch[int, string]
+	for i := 9; i >= 0; i-- {
+		v := "-" + strconv.Itoa(i) + "-"
+		batch.Add(i, &v)
+	}
+	batch.Remove(3)
+	batch.Remove(42)
+	results := wt.Apply(&batch)
+	if results[10] != nil || results[11] == nil {
+		t.Errorf("unexpected batch results %v", results)
+	}
+	if err := wt.Close(); err != nil {
+		t.Error(err)
+	}
+
+	bt := NewBTree[int, string](3)
+	wt, err = OpenWAL[int, string](path, bt, WALOptions{Sync: SyncEveryOp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.Close()
+	if bt.Count() != 9 || bt.Get(3) != nil {
+		t.Errorf("expected batch replayed from log, found %d entries", bt.Count())
+	}
+	if err := checkContains(bt, 3); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWAL_KeysWhileWriting(t *testing.T) {
+	wt, err := OpenWAL[int, string](filepath.Join(t.TempDir(), "tree.wal"), NewBTree[int, string](3), WALOptions{Sync: SyncBatch, BatchSize: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.Close()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = fillTree(wt, 500)
+	}()
+	for i := 0; i < 20; i++ {
+		prev := -1
+		for k := range wt.Keys(context.Background()) {
+			if k <= prev {
+				t.Fatalf("key %d out of order after %d", k, prev)
+			}
+			prev = k
+		}
+	}
+	<-done
+}
+
+func TestWAL_RewindFailedWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tree.wal")
+	wt, err := OpenWAL[int, string](path, NewBTree[int, string](3), WALOptions{Sync: SyncEveryOp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = fillTree(wt, 1)
+	w := wt.(*walTree[int, string])
+	offset, _ := w.file.Seek(0, io.SeekCurrent)
+	// the start of a record, as left by a short write
+	_, _ = w.file.Write([]byte{9, 0, 0, 0, 1})
+	w.rewind(offset, errors.New("short write"))
+	v := "-1-"
+	if err := wt.Add(1, &v); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.Close(); err != nil {
+		t.Error(err)
+	}
+	bt := NewBTree[int, string](3)
+	wt, err = OpenWAL[int, string](path, bt, WALOptions{Sync: SyncEveryOp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.Close()
+	if bt.Count() != 2 {
+		t.Errorf("expected both keys replayed after a rewound write, found %d", bt.Count())
+	}
+}
+
+func TestWAL_FailedLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tree.wal")
+	wt, err := OpenWAL[int, string](path, NewBTree[int, string](3), WALOptions{Sync: SyncEveryOp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = fillTree(wt, 1)
+	w := wt.(*walTree[int, string])
+	file := w.file
+	// a read only handle fails both the write, and the truncate to rewind it
+	w.file, err = os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.Add(1, new(string)); err == nil {
+		t.Fatal("expected error writing to read only log")
+	}
+	w.file.Close()
+	w.file = file
+	if err := wt.Add(2, new(string)); err == nil || wt.Get(2) != nil {
+		t.Error("expected failed log to refuse further writes")
+	}
+	if err := wt.Sync(); err == nil {
+		t.Error("expected failed log to report its error on sync")
+	}
+	if err := wt.Close(); err == nil {
+		t.Error("expected failed log to report its error on close")
+	}
+}
+
+func TestWAL_FailedSync(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tree.wal")
+	wt, err := OpenWAL[int, string](path, NewBTree[int, string](3), WALOptions{Sync: SyncEveryOp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = fillTree(wt, 5)
+	w := wt.(*walTree[int, string])
+	file := w.file
+	// writes to /dev/null succeed, but it can not be synced
+	if w.file, err = os.OpenFile(os.DevNull, os.O_RDWR, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.Add(9, new(string)); err == nil {
+		t.Fatal("expected error from failed sync")
+	}
+	if wt.Get(9) != nil {
+		t.Errorf("expected key %d not applied when its record fails to sync", 9)
+	}
+	w.file.Close()
+	w.file = file
+	// /dev/null can not be truncated either, so the record could not be rewound, and the log is failed
+	if err := wt.Sync(); err == nil {
+		t.Error("expected log failed, when the record of a failed write can not be rewound")
+	}
+	_ = wt.Close()
+
+	bt := NewBTree[int, string](3)
+	wt, err = OpenWAL[int, string](path, bt, WALOptions{Sync: SyncEveryOp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.Close()
+	if bt.Get(9) != nil || bt.Count() != 5 {
+		t.Errorf("expected only the %d synced writes replayed, found %d keys", 5, bt.Count())
+	}
+}
@@ -0,0 +1,266 @@
+package btree
+
+import (
+	"cmp"
+	"sync"
+)
+
+// EventType is the kind of change an Event reports.
+type EventType int
+
+const (
+	// EventInsert reports a key added to the tree.
+	EventInsert EventType = iota + 1
+	// EventUpdate reports a new value for a key already in the tree.
+	EventUpdate
+	// EventRemove reports a key removed from the tree.
+	EventRemove
+)
+
+func (et EventType) String() string {
+	switch et {
+	case EventInsert:
+		return "insert"
+	case EventUpdate:
+		return "update"
+	case EventRemove:
+		return "remove"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a change to a key in a watched tree.
+// Value is the new value of the key, nil for a removal. Old is the previous value, nil for an insert.
+type Event[K cmp.Ordered, V any] struct {
+	Type  EventType
+	Key   K
+	Value *V
+	Old   *V
+}
+
+// OverflowPolicy determines what happens to an event when a watchers buffer is full.
+type OverflowPolicy int
+
+const (
+	// OverflowDropNewest discards the new event.
+	OverflowDropNewest OverflowPolicy = iota
+	// OverflowDropOldest discards the oldest buffered event to make room for the new one.
+	OverflowDropOldest
+	// OverflowBlock blocks the write until the consumer makes room, or the watcher is closed.
+	// A consumer which writes to the tree while the buffer is full will deadlock.
+	OverflowBlock
+	// OverflowClose closes the watcher. Its consumer should re-read the tree, and watch it again.
+	OverflowClose
+)
+
+// defaultWatchBuffer is the size of a watchers buffer, when none is given.
+const defaultWatchBuffer = 64
+
+// WatchOptions configures a Watcher.
+type WatchOptions struct {
+	// Buffer is the number of events held for a consumer before the overflow policy applies.
+	Buffer   int
+	Overflow OverflowPolicy
+}
+
+// Watcher receives the events of a range of keys in a tree.
+type Watcher[K cmp.Ordered, V any] struct {
+	lo, hi     K
+	policy     OverflowPolicy
+	events     chan Event[K, V]
+	done       chan struct{}
+	list       *watchList[K, V]
+	stopOnce   sync.Once
+	mu         sync.Mutex
+	closed     bool
+	overflowed bool
+	dropped    uint64
+}
+
+// Events returns the channel of events. It is closed when the watcher is closed.
+func (w *Watcher[K, V]) Events() <-chan Event[K, V] {
+	return w.events
+}
+
+// Dropped returns the number of events discarded because the buffer was full.
+func (w *Watcher[K, V]) Dropped() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.dropped
+}
+
+// Overflowed returns true if the watcher was closed by the OverflowClose policy.
+func (w *Watcher[K, V]) Overflowed() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.overflowed
+}
+
+// Close stops the watcher and closes its events channel.
+func (w *Watcher[K, V]) Close() {
+	w.list.remove(w)
+	// release any write blocked on a full buffer
+	w.stopOnce.Do(func() {
+		close(w.done)
+	})
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.close()
+}
+
+// close closes the events channel. Caller must hold the watchers lock.
+func (w *Watcher[K, V]) close() {
+	if !w.closed {
+		w.closed = true
+		close(w.events)
+	}
+}
+
+func (w *Watcher[K, V]) watches(key K) bool {
+	return cmp.Compare(key, w.lo) >= 0 && cmp.Compare(key, w.hi) <= 0
+}
+
+func (w *Watcher[K, V]) send(e Event[K, V]) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
+	select {
+	case w.events <- e:
+		return
+	default:
+	}
+	switch w.policy {
+	case OverflowDropNewest:
+		w.dropped++
+	case OverflowDropOldest:
+		for {
+			select {
+			case w.events <- e:
+				return
+			default:
+			}
+			select {
+			case <-w.events:
+				w.dropped++
+			default:
+			}
+		}
+	case OverflowBlock:
+		select {
+		case w.events <- e:
+		case <-w.done:
+		}
+	case OverflowClose:
+		w.overflowed = true
+		w.close()
+		w.list.remove(w)
+	}
+}
+
+// watchList is the set of watchers on a tree.
+type watchList[K cmp.Ordered, V any] struct {
+	mu       sync.Mutex
+	watchers []*Watcher[K, V]
+	// held are the events held back while holding, to be sent, or dropped, when released.
+	held    []Event[K, V]
+	holding bool
+}
+
+func (wl *watchList[K, V]) add(w *Watcher[K, V]) {
+	wl.mu.Lock()
+	defer wl.mu.Unlock()
+	wl.watchers = append(wl.watchers, w)
+}
+
+func (wl *watchList[K, V]) remove(w *Watcher[K, V]) {
+	wl.mu.Lock()
+	defer wl.mu.Unlock()
+	if i := IndexOf(w, wl.watchers); i >= 0 {
+		wl.watchers = RemoveAtIndex(wl.watchers, i)
+	}
+}
+
+// notify sends the given event to every watcher of its key, or holds it back until released.
+func (wl *watchList[K, V]) notify(e Event[K, V]) {
+	wl.mu.Lock()
+	if wl.holding {
+		wl.held = append(wl.held, e)
+		wl.mu.Unlock()
+		return
+	}
+	watchers := wl.watchers
+	wl.mu.Unlock()
+	for _, w := range watchers {
+		if w.watches(e.Key) {
+			w.send(e)
+		}
+	}
+}
+
+// notifyWrite sends an insert, or an update when existed, to any watchers of the given key.
+func (wl *watchList[K, V]) notifyWrite(key K, value, old *V, existed bool) {
+	if !wl.active() {
+		return
+	}
+	e := Event[K, V]{Type: EventInsert, Key: key, Value: value}
+	if existed {
+		e.Type, e.Old = EventUpdate, old
+	}
+	wl.notify(e)
+}
+
+// notifyRemove sends a remove to any watchers of the given key.
+func (wl *watchList[K, V]) notifyRemove(key K, old *V) {
+	if wl.active() {
+		wl.notify(Event[K, V]{Type: EventRemove, Key: key, Old: old})
+	}
+}
+
+// hold holds back every event notified, until released.
+func (wl *watchList[K, V]) hold() {
+	wl.mu.Lock()
+	defer wl.mu.Unlock()
+	wl.holding = true
+}
+
+// release stops holding events, sending those held when send is true, or dropping them.
+func (wl *watchList[K, V]) release(send bool) {
+	wl.mu.Lock()
+	held := wl.held
+	wl.held, wl.holding = nil, false
+	wl.mu.Unlock()
+	if !send {
+		return
+	}
+	for _, e := range held {
+		wl.notify(e)
+	}
+}
+
+// active returns true if there are any watchers.
+func (wl *watchList[K, V]) active() bool {
+	wl.mu.Lock()
+	defer wl.mu.Unlock()
+	return len(wl.watchers) > 0
+}
+
+// watch creates a new watcher of the keys from lo to hi, inclusive.
+func (wl *watchList[K, V]) watch(lo, hi K, opts WatchOptions) *Watcher[K, V] {
+	size := opts.Buffer
+	if size < 1 {
+		size = defaultWatchBuffer
+	}
+	w := &Watcher[K, V]{
+		lo:     lo,
+		hi:     hi,
+		policy: opts.Overflow,
+		events: make(chan Event[K, V], size),
+		done:   make(chan struct{}),
+		list:   wl,
+	}
+	wl.add(w)
+	return w
+}
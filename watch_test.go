@@ -0,0 +1,143 @@
+package btree
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestBTree_Watch(t *testing.T) {
+	bt := createTestTree(3, 10)
+	w := bt.Watch(5, 20, WatchOptions{})
+	a, b := "a", "b"
+	_ = bt.Add(2, &a) // outside the range
+	_ = bt.Add(15, &a)
+	_ = bt.Add(15, &b)
+	_ = bt.Add(7, &a)
+	_ = bt.Remove(15)
+	_ = bt.Remove(99) // unknown, no event
+	var batch Batch[int, string]
+	batch.Add(20, &a)
+	batch.Add(8, &b)
+	batch.Remove(9)
+	bt.Apply(&batch)
+	w.Close()
+
+	expect := []Event[int, string]{
+		{Type: EventInsert, Key: 15, Value: &a},
+		{Type: EventUpdate, Key: 15, Value: &b, Old: &a},
+		{Type: EventUpdate, Key: 7, Value: &a},
+		{Type: EventRemove, Key: 15, Old: &b},
+		{Type: EventUpdate, Key: 8, Value: &b},
+		{Type: EventRemove, Key: 9},
+		{Type: EventInsert, Key: 20, Value: &a},
+	}
+	var events []Event[int, string]
+	for e := range w.Events() {
+		events = append(events, e)
+	}
+	if len(events) != len(expect) {
+		t.Fatalf("expected %d events, found %d  %v", len(expect), len(events), events)
+	}
+	for i, e := range events {
+		x := expect[i]
+		if e.Type != x.Type || e.Key != x.Key || (x.Value != nil && e.Value != x.Value) || (x.Old != nil && e.Old != x.Old) {
+			t.Errorf("expected event %d to be %s of %d, found %s of %d", i, x.Type, x.Key, e.Type, e.Key)
+		}
+	}
+	if events[2].Old == nil || *events[2].Old != "-7-" {
+		t.Errorf("expected old value of update event, got %v", events[2].Old)
+	}
+
+	// closed watchers receive nothing more
+	_ = bt.Add(10, &a)
+	if bt.watches.active() {
+		t.Error("expected no active watchers after close")
+	}
+}
+
+func TestBTree_Watch_RemoveOld(t *testing.T) {
+	bt := createTestTree(3, 50)
+	w := bt.Watch(0, 100, WatchOptions{Buffer: 50})
+	// keys held by parent nodes are replaced by their preceding key, before it is removed from its leaf
+	for _, k := range rand.New(rand.NewSource(1)).Perm(50) {
+		if err := bt.Remove(k); err != nil {
+			t.Error(err)
+		}
+	}
+	w.Close()
+	count := 0
+	for e := range w.Events() {
+		count++
+		if e.Type != EventRemove || e.Old == nil || *e.Old != "-"+strconv.Itoa(e.Key)+"-" {
+			t.Errorf("expected remove of key %d with its old value, found %s of %v", e.Key, e.Type, e.Old)
+		}
+	}
+	if count != 50 {
+		t.Errorf("expected %d remove events, found %d", 50, count)
+	}
+}
+
+func TestWatcher_Overflow(t *testing.T) {
+	bt := createTestTree(3, 0)
+	newest := bt.Watch(0, 100, WatchOptions{Buffer: 2, Overflow: OverflowDropNewest})
+	oldest := bt.Watch(0, 100, WatchOptions{Buffer: 2, Overflow: OverflowDropOldest})
+	closing := bt.Watch(0, 100, WatchOptions{Buffer: 2, Overflow: OverflowClose})
+	if err := fillTree(bt, 5); err != nil {
+		t.Error(err)
+	}
+	if newest.Dropped() != 3 || oldest.Dropped() != 3 {
+		t.Errorf("expected 3 events dropped, found %d and %d", newest.Dropped(), oldest.Dropped())
+	}
+	if e := <-newest.Events(); e.Key != 0 {
+		t.Errorf("expected oldest event kept by drop newest, found key %d", e.Key)
+	}
+	if e := <-oldest.Events(); e.Key != 3 {
+		t.Errorf("expected newest events kept by drop oldest, found key %d", e.Key)
+	}
+	if !closing.Overflowed() {
+		t.Error("expected watcher closed on overflow")
+	}
+	count := 0
+	for range closing.Events() {
+		count++
+	}
+	if count != 2 {
+		t.Errorf("expected %d buffered events before overflow, found %d", 2, count)
+	}
+	newest.Close()
+	oldest.Close()
+	closing.Close()
+}
+
+func TestWatcher_Block(t *testing.T) {
+	bt := createTestTree(3, 0)
+	w := bt.Watch(0, 100, WatchOptions{Buffer: 1, Overflow: OverflowBlock})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = fillTree(bt, 3)
+	}()
+	for i := 0; i < 3; i++ {
+		e := <-w.Events()
+		if e.Key != i {
+			t.Errorf("expected event for key %d, found %d", i, e.Key)
+		}
+	}
+	<-done
+
+	// a blocked write is released by closing the watcher
+	done = make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = fillTree(bt, 6)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	w.Close()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("expected blocked write released by closing the watcher")
+	}
+}